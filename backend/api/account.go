@@ -0,0 +1,129 @@
+package api
+
+import (
+	"backend/constants"
+	"backend/database"
+	"backend/database/entity"
+	"backend/database/functionality"
+	"backend/env"
+	"backend/loggers"
+	"backend/mailer"
+	"backend/validators"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type forgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type resetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password" validate:"testination-password"`
+}
+
+type tokenRequest struct {
+	Token string `json:"token"`
+}
+
+func emailLink(path string, token string) string {
+	return fmt.Sprintf("%s%s?token=%s", env.APP_URL, path, url.QueryEscape(token))
+}
+
+func sendVerificationEmail(db *database.FinalTestinationDB, player *entity.Player) error {
+	token, err := functionality.PlayerTokenCreate(db, player.ID, constants.TOKEN_PURPOSE_VERIFY_EMAIL)
+	if err != nil {
+		return err
+	}
+
+	return mailer.Current.Send(mailer.Message{
+		To:      player.Email,
+		Subject: "Verify your email",
+		Body: fmt.Sprintf("Hi %s,\n\nplease confirm your email by opening this link:\n%s\n\nThe link expires in 48 hours.\n",
+			player.Username, emailLink("/verify-email", token)),
+	})
+}
+
+// forgotPassword always answers in the same way, so that it cannot be used to find out which emails are registered
+func forgotPassword(c *fiber.Ctx) error {
+	db := c.Locals("db").(*database.FinalTestinationDB)
+	body := c.Locals("parsedBody").(forgotPasswordRequest)
+
+	player, err := functionality.PlayerGetByEmail(db, body.Email)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not retrieve user"})
+	}
+
+	if player != nil {
+		token, err := functionality.PlayerTokenCreate(db, player.ID, constants.TOKEN_PURPOSE_RESET_PASSWORD)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Couldn't create the reset link"})
+		}
+
+		err = mailer.Current.Send(mailer.Message{
+			To:      player.Email,
+			Subject: "Reset your password",
+			Body: fmt.Sprintf("Hi %s,\n\nyou can choose a new password by opening this link:\n%s\n\nThe link expires in one hour. If you did not ask to reset your password, ignore this email.\n",
+				player.Username, emailLink("/reset-password", token)),
+		})
+		if err != nil {
+			loggers.Error.Printf("Couldn't send the reset email to %s: %s", player.Email, err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Couldn't send the email, please try again later"})
+		}
+	}
+
+	return c.JSON(fiber.Map{"message": "If the email is registered, a link to reset the password has been sent"})
+}
+
+func resetPassword(c *fiber.Ctx) error {
+	db := c.Locals("db").(*database.FinalTestinationDB)
+	body := c.Locals("parsedBody").(resetPasswordRequest)
+
+	if err := validators.Validate.GetValidator().Struct(body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "The password must be between 8 and 30 characters long"})
+	}
+
+	err := functionality.PlayerResetPassword(db, body.Token, body.Password)
+	if errors.Is(err, functionality.ErrTokenInvalid) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Couldn't reset the password"})
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+func verifyEmail(c *fiber.Ctx) error {
+	db := c.Locals("db").(*database.FinalTestinationDB)
+	body := c.Locals("parsedBody").(tokenRequest)
+
+	err := functionality.PlayerVerifyEmail(db, body.Token)
+	if errors.Is(err, functionality.ErrTokenInvalid) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Couldn't verify the email"})
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+func resendVerificationEmail(c *fiber.Ctx) error {
+	db := c.Locals("db").(*database.FinalTestinationDB)
+	player := c.Locals("player").(entity.Player)
+
+	if player.EmailVerified {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "The email is already verified"})
+	}
+
+	if err := sendVerificationEmail(db, &player); err != nil {
+		loggers.Error.Printf("Couldn't send the verification email to %s: %s", player.Email, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Couldn't send the email, please try again later"})
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"backend/database"
+	"backend/env"
+	"backend/mailer"
+	"backend/utils"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+var emailToken = regexp.MustCompile(`token=(\S+)`)
+
+// tokenFromEmail returns the token of the link in the last email sent to `to`
+func tokenFromEmail(t *testing.T, mails *mailer.MemoryMailer, to string) string {
+	message, ok := mails.Last(to)
+	assert.True(t, ok, "An email was sent to %s", to)
+
+	match := emailToken.FindStringSubmatch(message.Body)
+	assert.NotNil(t, match, "The email contains a link with a token")
+	if match == nil {
+		return ""
+	}
+
+	token, err := url.QueryUnescape(match[1])
+	assert.NoError(t, err)
+	return token
+}
+
+func postJSON(t *testing.T, app *fiber.App, route string, body any, cookies ...*http.Cookie) *http.Response {
+	jsonBody, _ := json.Marshal(body)
+	req := httptest.NewRequest("POST", route, bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+
+	resp, err := app.Test(req, -1) // -1 means no timeout
+	assert.NoError(t, err)
+	return resp
+}
+
+func TestAccountEmails(t *testing.T) {
+	db := database.CreateFinalTestinationDB(
+		env.DB_USERNAME, env.DB_PASSWORD,
+		env.DB_HOST, env.DB_PORT, env.DB_NAME)
+	db.CreateSchemas()
+
+	app := fiber.New()
+	playerGroup := app.Group("/player")
+	SetUpPlayerRoutes(&playerGroup, db)
+
+	mails := &mailer.MemoryMailer{}
+	mailer.Current = mails
+
+	username := fmt.Sprintf("mailtest%d", rand.Intn(1000000))
+	email := username + "@testination.com"
+
+	resp := postJSON(t, app, "/player/register", map[string]string{"username": username, "password": "rootroot", "email": email})
+	assert.Equal(t, 200, resp.StatusCode, "Register a new player")
+	verifyToken := tokenFromEmail(t, mails, email)
+
+	// Email verification
+	login := readSessionCookies(utils.MockLogin(t, app, username, "rootroot"))
+	resp = sessionRequest(t, app, "GET", "/player/profile", login)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Contains(t, string(body), `"emailVerified":false`, "A new player is not verified")
+
+	resp = postJSON(t, app, "/player/verify-email", map[string]string{"token": "not a token"})
+	assert.Equal(t, 400, resp.StatusCode, "Verify with an invalid token")
+
+	resp = postJSON(t, app, "/player/verify-email", map[string]string{"token": verifyToken})
+	assert.Equal(t, 200, resp.StatusCode, "Verify the email")
+
+	resp = postJSON(t, app, "/player/verify-email", map[string]string{"token": verifyToken})
+	assert.Equal(t, 400, resp.StatusCode, "A verification token can only be used once")
+
+	resp = sessionRequest(t, app, "GET", "/player/profile", login)
+	body, _ = io.ReadAll(resp.Body)
+	assert.Contains(t, string(body), `"emailVerified":true`, "The player is now verified")
+
+	resp = sessionRequest(t, app, "POST", "/player/resend-verification", login)
+	assert.Equal(t, 409, resp.StatusCode, "A verified player cannot ask for another verification email")
+
+	// Password reset
+	resp = postJSON(t, app, "/player/forgot-password", map[string]string{"email": "nobody@testination.com"})
+	assert.Equal(t, 200, resp.StatusCode, "Asking a reset for an unknown email looks successful")
+	_, sent := mails.Last("nobody@testination.com")
+	assert.False(t, sent, "No email is sent to unknown addresses")
+
+	resp = postJSON(t, app, "/player/forgot-password", map[string]string{"email": email})
+	assert.Equal(t, 200, resp.StatusCode, "Ask for a password reset")
+	firstResetToken := tokenFromEmail(t, mails, email)
+
+	resp = postJSON(t, app, "/player/forgot-password", map[string]string{"email": email})
+	assert.Equal(t, 200, resp.StatusCode, "Ask for a password reset again")
+	resetToken := tokenFromEmail(t, mails, email)
+
+	resp = postJSON(t, app, "/player/reset-password", map[string]string{"token": firstResetToken, "password": "newpassword"})
+	assert.Equal(t, 400, resp.StatusCode, "A new reset email invalidates the previous one")
+
+	resp = postJSON(t, app, "/player/reset-password", map[string]string{"token": verifyToken, "password": "newpassword"})
+	assert.Equal(t, 400, resp.StatusCode, "A verification token cannot reset the password")
+
+	resp = postJSON(t, app, "/player/reset-password", map[string]string{"token": resetToken, "password": "short"})
+	assert.Equal(t, 400, resp.StatusCode, "The new password must be valid")
+
+	resp = postJSON(t, app, "/player/reset-password", map[string]string{"token": resetToken, "password": "newpassword"})
+	assert.Equal(t, 200, resp.StatusCode, "Reset the password")
+
+	resp = postJSON(t, app, "/player/reset-password", map[string]string{"token": resetToken, "password": "otherpassword"})
+	assert.Equal(t, 400, resp.StatusCode, "A reset token can only be used once")
+
+	resp = sessionRequest(t, app, "GET", "/player/profile", login)
+	assert.Equal(t, 401, resp.StatusCode, "Resetting the password revokes the sessions")
+
+	assert.Equal(t, 401, utils.MockLogin(t, app, username, "rootroot").StatusCode, "The old password does not work anymore")
+	assert.Equal(t, 200, utils.MockLogin(t, app, username, "newpassword").StatusCode, "The new password works")
+}
@@ -0,0 +1,387 @@
+package api
+
+import (
+	"backend/constants"
+	"backend/database"
+	"backend/database/entity"
+	"backend/database/functionality"
+	"backend/levels"
+	"backend/middlewares"
+	"backend/scoring"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type blocksRequest struct {
+	Blocks []entity.Block `json:"blocks"`
+}
+
+// The scoring policy is optional, the one of the game is used when it is missing
+type scoringPreviewRequest struct {
+	Seconds  int64           `json:"seconds"`
+	Attempts int             `json:"attempts"`
+	Hints    []string        `json:"hints"`
+	Scoring  *scoring.Policy `json:"scoring"`
+}
+
+// coinGrantRequest gives coins to a player, `kind` is either "grant" or "refund"
+type coinGrantRequest struct {
+	Kind   string     `json:"kind"`
+	Amount int        `json:"amount"`
+	Reason string     `json:"reason"`
+	GameID *uuid.UUID `json:"game_id"`
+}
+
+type roleRequest struct {
+	Role string `json:"role"`
+}
+
+func SetUpAdminRoutes(router *fiber.Router, database *database.FinalTestinationDB) {
+	(*router).Use(
+		middlewares.InjectDB(database),
+		middlewares.ValidateJWT,
+		middlewares.CheckValidPlayer,
+	)
+
+	(*router).Get("/games",
+		middlewares.RequireRole(constants.ROLE_AUTHOR),
+		listGames,
+	)
+	(*router).Post("/games",
+		middlewares.RequireRole(constants.ROLE_AUTHOR),
+		middlewares.ParseBodyAsJSON[entity.Game],
+		createGame,
+	)
+	(*router).Get("/games/:gameId",
+		middlewares.RequireRole(constants.ROLE_AUTHOR),
+		middlewares.CheckValidUUID("gameId"),
+		getGameForAdmin,
+	)
+	(*router).Put("/games/:gameId",
+		middlewares.RequireRole(constants.ROLE_AUTHOR),
+		middlewares.CheckValidUUID("gameId"),
+		middlewares.ParseBodyAsJSON[entity.Game],
+		updateGame,
+	)
+	(*router).Delete("/games/:gameId",
+		middlewares.RequireRole(constants.ROLE_AUTHOR),
+		middlewares.CheckValidUUID("gameId"),
+		deleteGame,
+	)
+	(*router).Post("/games/import",
+		middlewares.RequireRole(constants.ROLE_AUTHOR),
+		importGame,
+	)
+	(*router).Get("/games/:gameId/export",
+		middlewares.RequireRole(constants.ROLE_AUTHOR),
+		middlewares.CheckValidUUID("gameId"),
+		exportGame,
+	)
+	(*router).Put("/games/:gameId/blocks",
+		middlewares.RequireRole(constants.ROLE_AUTHOR),
+		middlewares.CheckValidUUID("gameId"),
+		middlewares.ParseBodyAsJSON[blocksRequest],
+		replaceBlocks,
+	)
+
+	(*router).Get("/games/:gameId/attempts",
+		middlewares.RequireRole(constants.ROLE_AUTHOR),
+		middlewares.CheckValidUUID("gameId"),
+		getAttemptsSummary,
+	)
+	(*router).Get("/games/:gameId/stats",
+		middlewares.RequireRole(constants.ROLE_AUTHOR),
+		middlewares.CheckValidUUID("gameId"),
+		getGameStats,
+	)
+
+	(*router).Post("/games/:gameId/scoring/preview",
+		middlewares.RequireRole(constants.ROLE_AUTHOR),
+		middlewares.CheckValidUUID("gameId"),
+		middlewares.ParseBodyAsJSON[scoringPreviewRequest],
+		previewScoring,
+	)
+
+	(*router).Put("/players/:playerId/role",
+		middlewares.RequireRole(constants.ROLE_ADMIN),
+		middlewares.CheckValidUUID("playerId"),
+		middlewares.ParseBodyAsJSON[roleRequest],
+		setPlayerRole,
+	)
+	(*router).Post("/players/:playerId/coins",
+		middlewares.RequireRole(constants.ROLE_ADMIN),
+		middlewares.CheckValidUUID("playerId"),
+		middlewares.ParseBodyAsJSON[coinGrantRequest],
+		grantCoins,
+	)
+}
+
+// gameErrorResponse maps the errors returned by the game functionalities to an HTTP response
+func gameErrorResponse(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, functionality.ErrInvalidGame):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, functionality.ErrGameNotFound), errors.Is(err, gorm.ErrRecordNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Couldn't find the game you're looking for"})
+	case errors.Is(err, functionality.ErrGameOrderTaken), errors.Is(err, functionality.ErrGameHasPlayers):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Couldn't save the game, please try again later"})
+	}
+}
+
+func listGames(c *fiber.Ctx) error {
+	db := c.Locals("db").(*database.FinalTestinationDB)
+
+	games, err := functionality.GameGetAll(db)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Couldn't get the games",
+		})
+	}
+
+	return c.JSON(games)
+}
+
+func getGameForAdmin(c *fiber.Ctx) error {
+	gameId := c.Locals("gameId").(uuid.UUID)
+	db := c.Locals("db").(*database.FinalTestinationDB)
+
+	game, err := functionality.GameGetById(db, gameId)
+	if err != nil {
+		return gameErrorResponse(c, err)
+	}
+
+	return c.JSON(game)
+}
+
+func createGame(c *fiber.Ctx) error {
+	db := c.Locals("db").(*database.FinalTestinationDB)
+	game := c.Locals("parsedBody").(entity.Game)
+
+	if err := functionality.GameCreate(db, &game); err != nil {
+		return gameErrorResponse(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(game)
+}
+
+func updateGame(c *fiber.Ctx) error {
+	gameId := c.Locals("gameId").(uuid.UUID)
+	db := c.Locals("db").(*database.FinalTestinationDB)
+	game := c.Locals("parsedBody").(entity.Game)
+
+	if err := functionality.GameUpdate(db, gameId, &game); err != nil {
+		return gameErrorResponse(c, err)
+	}
+
+	updated, err := functionality.GameGetById(db, gameId)
+	if err != nil {
+		return gameErrorResponse(c, err)
+	}
+
+	return c.JSON(updated)
+}
+
+func replaceBlocks(c *fiber.Ctx) error {
+	gameId := c.Locals("gameId").(uuid.UUID)
+	db := c.Locals("db").(*database.FinalTestinationDB)
+	body := c.Locals("parsedBody").(blocksRequest)
+
+	if body.Blocks == nil {
+		body.Blocks = []entity.Block{}
+	}
+
+	if err := functionality.GameReplaceBlocks(db, gameId, body.Blocks); err != nil {
+		return gameErrorResponse(c, err)
+	}
+
+	return c.JSON(body.Blocks)
+}
+
+func deleteGame(c *fiber.Ctx) error {
+	gameId := c.Locals("gameId").(uuid.UUID)
+	db := c.Locals("db").(*database.FinalTestinationDB)
+
+	if err := functionality.GameDelete(db, gameId); err != nil {
+		return gameErrorResponse(c, err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// importGame creates or overwrites a game from a level package. The package is read as YAML
+// when the content type says so, and as JSON otherwise.
+func importGame(c *fiber.Ctx) error {
+	db := c.Locals("db").(*database.FinalTestinationDB)
+
+	format := levels.FORMAT_JSON
+	if strings.Contains(c.Get(fiber.HeaderContentType), "yaml") {
+		format = levels.FORMAT_YAML
+	}
+
+	pkg, err := levels.Decode(c.Body(), format)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	game := pkg.Game()
+	created, err := functionality.GameImport(db, &game)
+	if err != nil {
+		return gameErrorResponse(c, err)
+	}
+
+	status := fiber.StatusOK
+	if created {
+		status = fiber.StatusCreated
+	}
+	return c.Status(status).JSON(game)
+}
+
+func exportGame(c *fiber.Ctx) error {
+	gameId := c.Locals("gameId").(uuid.UUID)
+	db := c.Locals("db").(*database.FinalTestinationDB)
+
+	format := c.Query("format", levels.FORMAT_YAML)
+	if format != levels.FORMAT_YAML && format != levels.FORMAT_JSON {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "The format must be either yaml or json",
+		})
+	}
+
+	game, err := functionality.GameGetById(db, gameId)
+	if err != nil {
+		return gameErrorResponse(c, err)
+	}
+
+	pkg := levels.FromGame(game)
+	data, err := levels.Encode(&pkg, format)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Couldn't export the game",
+		})
+	}
+
+	c.Attachment(fmt.Sprintf("%s.%s", game.ID, format))
+	c.Set(fiber.HeaderContentType, "application/"+format)
+	return c.Send(data)
+}
+
+func getAttemptsSummary(c *fiber.Ctx) error {
+	gameId := c.Locals("gameId").(uuid.UUID)
+	db := c.Locals("db").(*database.FinalTestinationDB)
+
+	if _, err := functionality.GameGetById(db, gameId); err != nil {
+		return gameErrorResponse(c, err)
+	}
+
+	summary, err := functionality.AttemptsSummary(db, gameId)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Couldn't get the attempts",
+		})
+	}
+
+	return c.JSON(summary)
+}
+
+func getGameStats(c *fiber.Ctx) error {
+	gameId := c.Locals("gameId").(uuid.UUID)
+	db := c.Locals("db").(*database.FinalTestinationDB)
+
+	if _, err := functionality.GameGetById(db, gameId); err != nil {
+		return gameErrorResponse(c, err)
+	}
+
+	stats, err := functionality.GameStats(db, gameId)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Couldn't get the statistics of the game",
+		})
+	}
+
+	return c.JSON(stats)
+}
+
+func previewScoring(c *fiber.Ctx) error {
+	gameId := c.Locals("gameId").(uuid.UUID)
+	db := c.Locals("db").(*database.FinalTestinationDB)
+	body := c.Locals("parsedBody").(scoringPreviewRequest)
+
+	game, err := functionality.GameGetById(db, gameId)
+	if err != nil {
+		return gameErrorResponse(c, err)
+	}
+
+	policy := game.Scoring
+	if body.Scoring != nil {
+		policy = *body.Scoring
+	}
+
+	preview, err := functionality.GameScoringPreview(game, policy, body.Seconds, body.Attempts, body.Hints)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(preview)
+}
+
+func setPlayerRole(c *fiber.Ctx) error {
+	db := c.Locals("db").(*database.FinalTestinationDB)
+	body := c.Locals("parsedBody").(roleRequest)
+
+	if !slices.Contains(constants.ROLES, body.Role) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid role",
+		})
+	}
+
+	err := functionality.PlayerSetRole(db, c.Locals("playerId").(uuid.UUID), body.Role)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Couldn't find the player you're looking for",
+			})
+		}
+		if errors.Is(err, functionality.ErrLastAdmin) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "The last admin cannot lose its role, promote another admin first",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Couldn't update the role of the player",
+		})
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// grantCoins records a grant or a refund in the coin ledger of a player, referencing the admin that made it
+func grantCoins(c *fiber.Ctx) error {
+	db := c.Locals("db").(*database.FinalTestinationDB)
+	admin := c.Locals("player").(entity.Player)
+	playerID := c.Locals("playerId").(uuid.UUID)
+	body := c.Locals("parsedBody").(coinGrantRequest)
+
+	transaction, err := functionality.CoinGrant(db, playerID, body.Kind, body.Amount, body.Reason, body.GameID, &admin.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, functionality.ErrInvalidCoinTransaction):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Couldn't find the player you're looking for"})
+		case errors.Is(err, functionality.ErrGameNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Couldn't find the game you're looking for"})
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Couldn't give the coins to the player"})
+		}
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(transaction)
+}
@@ -0,0 +1,512 @@
+package api
+
+import (
+	"backend/constants"
+	"backend/database"
+	"backend/database/functionality"
+	"backend/env"
+	"backend/utils"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+var adminGameBody string = `{
+	"title": "Admin test level",
+	"game_order": 1000,
+	"story": "story",
+	"cheatsheet": "cheatsheet",
+	"max_score": 100,
+	"description": "description",
+	"background": "<svg></svg>",
+	"winning_message": "well done",
+	"wrong_attempt_cost": 5,
+	"perfect_timeslot": 60,
+	"great_timeslot": 120,
+	"medium_timeslot": 180,
+	"not_so_good_timeslot": 240,
+	"textual_hints": [{"content": "hint", "price": 10}],
+	"hint_solution_price": 10,
+	"time_freeze_price": 10,
+	"time_freeze_duration": 30,
+	"blocks": [
+		{"content": "SELECT", "order": 0, "skeleton": true},
+		{"content": "*", "order": 1},
+		{"content": "DROP", "order": null}
+	]
+}`
+
+type adminGameResponse struct {
+	ID        string `json:"ID"`
+	Title     string `json:"title"`
+	GameOrder int    `json:"game_order"`
+	Blocks    []struct {
+		Content  string `json:"content"`
+		Order    *uint  `json:"order"`
+		Skeleton bool   `json:"skeleton"`
+	} `json:"blocks"`
+}
+
+func TestAdminGames(t *testing.T) {
+	tests := []struct {
+		method       string
+		description  string
+		route        string // `{id}` is replaced with the ID of the game created by the first test
+		expectedCode int
+		body         string
+	}{
+		{
+			method:       "POST",
+			description:  "Create a game",
+			route:        "/admin/games",
+			expectedCode: 201,
+			body:         adminGameBody,
+		},
+		{
+			method:       "GET",
+			description:  "Get the created game with its blocks",
+			route:        "/admin/games/{id}",
+			expectedCode: 200,
+		},
+		{
+			method:       "POST",
+			description:  "Create a game with an order that is already taken",
+			route:        "/admin/games",
+			expectedCode: 409,
+			body:         strings.Replace(adminGameBody, `"game_order": 1000`, `"game_order": 1`, 1),
+		},
+		{
+			method:       "POST",
+			description:  "Create a game with a skeleton block without order",
+			route:        "/admin/games",
+			expectedCode: 400,
+			body:         strings.Replace(adminGameBody, `"order": 0, "skeleton": true`, `"order": null, "skeleton": true`, 1),
+		},
+		{
+			method:       "PUT",
+			description:  "Update the created game",
+			route:        "/admin/games/{id}",
+			expectedCode: 200,
+			body:         strings.Replace(adminGameBody, "Admin test level", "Admin test level (edited)", 1),
+		},
+		{
+			method:       "PUT",
+			description:  "Replace the blocks with a non contiguous solution",
+			route:        "/admin/games/{id}/blocks",
+			expectedCode: 400,
+			body:         `{"blocks": [{"content": "SELECT", "order": 0}, {"content": "*", "order": 2}]}`,
+		},
+		{
+			method:       "PUT",
+			description:  "Replace the blocks",
+			route:        "/admin/games/{id}/blocks",
+			expectedCode: 200,
+			body:         `{"blocks": [{"content": "SELECT", "order": 0}, {"content": "*", "order": 1}]}`,
+		},
+		{
+			method:       "PUT",
+			description:  "Add an alternative solution",
+			route:        "/admin/games/{id}",
+			expectedCode: 200,
+			body:         strings.Replace(adminGameBody, `"blocks": [`, `"alternative_solutions": [["SELECT", "DROP"]], "blocks": [`, 1),
+		},
+		{
+			method:       "PUT",
+			description:  "Add an alternative solution longer than the solution",
+			route:        "/admin/games/{id}",
+			expectedCode: 400,
+			body:         strings.Replace(adminGameBody, `"blocks": [`, `"alternative_solutions": [["SELECT", "*", "*"]], "blocks": [`, 1),
+		},
+		{
+			method:       "PUT",
+			description:  "Replace the blocks removing one used by an alternative solution",
+			route:        "/admin/games/{id}/blocks",
+			expectedCode: 400,
+			body:         `{"blocks": [{"content": "SELECT", "order": 0, "skeleton": true}, {"content": "*", "order": 1}]}`,
+		},
+		{
+			method:       "GET",
+			description:  "List the games",
+			route:        "/admin/games",
+			expectedCode: 200,
+		},
+		{
+			method:       "GET",
+			description:  "Get the statistics of a game nobody played",
+			route:        "/admin/games/{id}/stats",
+			expectedCode: 200,
+		},
+		{
+			method:       "POST",
+			description:  "Preview the score with the policy of the game",
+			route:        "/admin/games/{id}/scoring/preview",
+			expectedCode: 200,
+			body:         `{"seconds": 70, "attempts": 1, "hints": ["freeze", "textual"]}`,
+		},
+		{
+			method:       "POST",
+			description:  "Preview the score with another policy",
+			route:        "/admin/games/{id}/scoring/preview",
+			expectedCode: 200,
+			body:         `{"seconds": 70, "attempts": 1, "scoring": {"curve": "buckets", "buckets": [{"up_to": 30, "multiplier": 1}], "floor": 0, "penalty": "none"}}`,
+		},
+		{
+			method:       "POST",
+			description:  "Preview the score with an invalid policy",
+			route:        "/admin/games/{id}/scoring/preview",
+			expectedCode: 400,
+			body:         `{"seconds": 70, "scoring": {"curve": "decay"}}`,
+		},
+		{
+			method:       "GET",
+			description:  "Get the statistics of a played game",
+			route:        "/admin/games/af8e4754-1b84-4fec-bec4-154a3f894b8f/stats",
+			expectedCode: 200,
+		},
+		{
+			method:       "DELETE",
+			description:  "Delete a game that has already been played",
+			route:        "/admin/games/af8e4754-1b84-4fec-bec4-154a3f894b8f",
+			expectedCode: 409,
+		},
+		{
+			method:       "DELETE",
+			description:  "Delete the created game",
+			route:        "/admin/games/{id}",
+			expectedCode: 204,
+		},
+		{
+			method:       "DELETE",
+			description:  "Delete a game that does not exist",
+			route:        "/admin/games/{id}",
+			expectedCode: 404,
+		},
+	}
+
+	db := database.CreateFinalTestinationDB(
+		env.DB_USERNAME, env.DB_PASSWORD,
+		env.DB_HOST, env.DB_PORT, env.DB_NAME)
+	db.CreateSchemas()
+
+	app := fiber.New()
+	adminGroup := app.Group("/admin")
+	SetUpAdminRoutes(&adminGroup, db)
+	playerGroup := app.Group("/player")
+	SetUpPlayerRoutes(&playerGroup, db)
+
+	loginResp := utils.MockLogin(t, app, "admin", "rootroot")
+	cookie := utils.Filter(loginResp.Cookies(), func(c *http.Cookie) bool {
+		return c.Name == constants.AUTH_COOKIE_NAME
+	})[0].Value
+
+	var gameID string
+	for _, test := range tests {
+		route := strings.Replace(test.route, "{id}", gameID, 1)
+		req := httptest.NewRequest(test.method, route, bytes.NewBuffer([]byte(test.body)))
+		req.Header.Set("Content-Type", "application/json")
+		req.AddCookie(&http.Cookie{Name: constants.AUTH_COOKIE_NAME, Value: cookie})
+
+		resp, err := app.Test(req, -1) // -1 means no timeout
+
+		assert.NoError(t, err)
+		assert.Equalf(t, test.expectedCode, resp.StatusCode, test.description)
+
+		responseBody, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+
+		if test.description == "Create a game" {
+			var parsedResponseBody adminGameResponse
+			err = json.Unmarshal(responseBody, &parsedResponseBody)
+			assert.NoError(t, err)
+			assert.NotEmpty(t, parsedResponseBody.ID, test.description)
+			gameID = parsedResponseBody.ID
+		} else if test.description == "Get the created game with its blocks" {
+			var parsedResponseBody adminGameResponse
+			err = json.Unmarshal(responseBody, &parsedResponseBody)
+			assert.NoError(t, err)
+			assert.Equalf(t, "Admin test level", parsedResponseBody.Title, test.description)
+			assert.Equalf(t, 1000, parsedResponseBody.GameOrder, test.description)
+			assert.Equalf(t, 3, len(parsedResponseBody.Blocks), test.description)
+		} else if test.description == "Update the created game" {
+			var parsedResponseBody adminGameResponse
+			err = json.Unmarshal(responseBody, &parsedResponseBody)
+			assert.NoError(t, err)
+			assert.Equalf(t, "Admin test level (edited)", parsedResponseBody.Title, test.description)
+		} else if test.description == "Preview the score with the policy of the game" {
+			var preview functionality.ScoringPreviewDTO
+			err = json.Unmarshal(responseBody, &preview)
+			assert.NoError(t, err)
+			assert.Equalf(t, functionality.ScoringPreviewDTO{Score: 95, Multiplier: 1, HintCost: 20, Coins: 75}, preview, test.description)
+		} else if test.description == "Preview the score with another policy" {
+			var preview functionality.ScoringPreviewDTO
+			err = json.Unmarshal(responseBody, &preview)
+			assert.NoError(t, err)
+			assert.Equalf(t, 0, preview.Score, test.description)
+		} else if test.description == "Get the statistics of a game nobody played" {
+			var stats functionality.GameStatsDTO
+			err = json.Unmarshal(responseBody, &stats)
+			assert.NoError(t, err)
+			assert.Equalf(t, 0, stats.Players, test.description)
+			assert.Equalf(t, 0.0, stats.CompletionRate, test.description)
+		} else if test.description == "Get the statistics of a played game" {
+			var stats functionality.GameStatsDTO
+			err = json.Unmarshal(responseBody, &stats)
+			assert.NoError(t, err)
+			assert.Greaterf(t, stats.Players, 0, test.description)
+			completions := 0
+			for _, timeslot := range stats.Timeslots {
+				completions += timeslot.Completions
+			}
+			assert.Equalf(t, stats.Completed, completions, "Every completion falls in a timeslot")
+		}
+	}
+}
+
+func TestAdminRoles(t *testing.T) {
+	tests := []struct {
+		method       string
+		description  string
+		username     string
+		route        string
+		expectedCode int
+		body         string
+	}{
+		{
+			method:       "GET",
+			description:  "A player cannot access the admin games",
+			username:     "test",
+			route:        "/admin/games",
+			expectedCode: 403,
+		},
+		{
+			method:       "PUT",
+			description:  "A player cannot change roles",
+			username:     "test",
+			route:        "/admin/players/7f57e6ae-c66c-42e7-b878-72c79ba131e9/role",
+			expectedCode: 403,
+			body:         `{"role": "admin"}`,
+		},
+		{
+			method:       "PUT",
+			description:  "An admin cannot assign a role that does not exist",
+			username:     "admin",
+			route:        "/admin/players/7f57e6ae-c66c-42e7-b878-72c79ba131e9/role",
+			expectedCode: 400,
+			body:         `{"role": "superuser"}`,
+		},
+		{
+			method:       "PUT",
+			description:  "An admin cannot change the role of a player that does not exist",
+			username:     "admin",
+			route:        "/admin/players/0987afd7-474b-4308-9f2f-447a0995a1ae/role",
+			expectedCode: 404,
+			body:         `{"role": "author"}`,
+		},
+		{
+			method:       "PUT",
+			description:  "An admin cannot change the role of an invalid player ID",
+			username:     "admin",
+			route:        "/admin/players/not-a-uuid/role",
+			expectedCode: 400,
+			body:         `{"role": "author"}`,
+		},
+		{
+			method:       "PUT",
+			description:  "The last admin cannot demote itself",
+			username:     "admin",
+			route:        "/admin/players/a977b9b6-00dd-43de-b9ad-bd1c41ff20be/role",
+			expectedCode: 409,
+			body:         `{"role": "player"}`,
+		},
+		{
+			method:       "PUT",
+			description:  "An admin promotes a player to author",
+			username:     "admin",
+			route:        "/admin/players/7f57e6ae-c66c-42e7-b878-72c79ba131e9/role",
+			expectedCode: 200,
+			body:         `{"role": "author"}`,
+		},
+		{
+			method:       "GET",
+			description:  "An author can access the admin games",
+			username:     "newUser",
+			route:        "/admin/games",
+			expectedCode: 200,
+		},
+		{
+			method:       "PUT",
+			description:  "An author cannot change roles",
+			username:     "newUser",
+			route:        "/admin/players/7f57e6ae-c66c-42e7-b878-72c79ba131e9/role",
+			expectedCode: 403,
+			body:         `{"role": "admin"}`,
+		},
+		{
+			method:       "PUT",
+			description:  "An admin demotes the author back to player",
+			username:     "admin",
+			route:        "/admin/players/7f57e6ae-c66c-42e7-b878-72c79ba131e9/role",
+			expectedCode: 200,
+			body:         `{"role": "player"}`,
+		},
+	}
+
+	db := database.CreateFinalTestinationDB(
+		env.DB_USERNAME, env.DB_PASSWORD,
+		env.DB_HOST, env.DB_PORT, env.DB_NAME)
+	db.CreateSchemas()
+
+	app := fiber.New()
+	adminGroup := app.Group("/admin")
+	SetUpAdminRoutes(&adminGroup, db)
+	playerGroup := app.Group("/player")
+	SetUpPlayerRoutes(&playerGroup, db)
+
+	for _, test := range tests {
+		loginResp := utils.MockLogin(t, app, test.username, "rootroot")
+		cookie := utils.Filter(loginResp.Cookies(), func(c *http.Cookie) bool {
+			return c.Name == constants.AUTH_COOKIE_NAME
+		})[0].Value
+
+		req := httptest.NewRequest(test.method, test.route, bytes.NewBuffer([]byte(test.body)))
+		req.Header.Set("Content-Type", "application/json")
+		req.AddCookie(&http.Cookie{Name: constants.AUTH_COOKIE_NAME, Value: cookie})
+
+		resp, err := app.Test(req, -1) // -1 means no timeout
+
+		assert.NoError(t, err)
+		assert.Equalf(t, test.expectedCode, resp.StatusCode, test.description)
+	}
+}
+
+var levelPackageYAML string = `id: 3f1f3c8e-0d7c-4d52-9a55-1c0de1e2a001
+title: Imported level
+game_order: 1001
+description: description
+story: story
+cheatsheet: cheatsheet
+background: <svg></svg>
+winning_message: well done
+max_score: 100
+wrong_attempt_cost: 5
+timeslots:
+  perfect: 60
+  great: 120
+  medium: 180
+  not_so_good: 240
+hints:
+  textual:
+    - content: first hint
+      price: 5
+    - content: second hint
+      price: 10
+  solution:
+    price: 10
+  time_freeze:
+    price: 10
+    duration: 30
+blocks:
+  solution:
+    - content: SELECT
+      skeleton: true
+    - content: '*'
+  decoys:
+    - DROP
+`
+
+func TestAdminLevelPackages(t *testing.T) {
+	tests := []struct {
+		method       string
+		description  string
+		route        string
+		contentType  string
+		expectedCode int
+		body         string
+	}{
+		{
+			method:       "POST",
+			description:  "Import a new level",
+			route:        "/admin/games/import",
+			contentType:  "application/yaml",
+			expectedCode: 201,
+			body:         levelPackageYAML,
+		},
+		{
+			method:       "POST",
+			description:  "Import the same level a second time",
+			route:        "/admin/games/import",
+			contentType:  "application/yaml",
+			expectedCode: 200,
+			body:         levelPackageYAML,
+		},
+		{
+			method:       "POST",
+			description:  "Import a level without ID",
+			route:        "/admin/games/import",
+			contentType:  "application/json",
+			expectedCode: 400,
+			body:         `{"title": "Imported level"}`,
+		},
+		{
+			method:       "GET",
+			description:  "Export the imported level",
+			route:        "/admin/games/3f1f3c8e-0d7c-4d52-9a55-1c0de1e2a001/export",
+			expectedCode: 200,
+		},
+		{
+			method:       "GET",
+			description:  "Export a level in an unknown format",
+			route:        "/admin/games/3f1f3c8e-0d7c-4d52-9a55-1c0de1e2a001/export?format=xml",
+			expectedCode: 400,
+		},
+		{
+			method:       "DELETE",
+			description:  "Delete the imported level",
+			route:        "/admin/games/3f1f3c8e-0d7c-4d52-9a55-1c0de1e2a001",
+			expectedCode: 204,
+		},
+	}
+
+	db := database.CreateFinalTestinationDB(
+		env.DB_USERNAME, env.DB_PASSWORD,
+		env.DB_HOST, env.DB_PORT, env.DB_NAME)
+	db.CreateSchemas()
+
+	app := fiber.New()
+	adminGroup := app.Group("/admin")
+	SetUpAdminRoutes(&adminGroup, db)
+	playerGroup := app.Group("/player")
+	SetUpPlayerRoutes(&playerGroup, db)
+
+	loginResp := utils.MockLogin(t, app, "admin", "rootroot")
+	cookie := utils.Filter(loginResp.Cookies(), func(c *http.Cookie) bool {
+		return c.Name == constants.AUTH_COOKIE_NAME
+	})[0].Value
+
+	for _, test := range tests {
+		req := httptest.NewRequest(test.method, test.route, bytes.NewBuffer([]byte(test.body)))
+		req.Header.Set("Content-Type", test.contentType)
+		req.AddCookie(&http.Cookie{Name: constants.AUTH_COOKIE_NAME, Value: cookie})
+
+		resp, err := app.Test(req, -1) // -1 means no timeout
+
+		assert.NoError(t, err)
+		assert.Equalf(t, test.expectedCode, resp.StatusCode, test.description)
+
+		responseBody, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+
+		if test.description == "Export the imported level" {
+			assert.Equalf(t, levelPackageYAML, string(responseBody), test.description)
+		}
+	}
+}
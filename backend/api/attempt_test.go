@@ -0,0 +1,104 @@
+package api
+
+import (
+	"backend/constants"
+	"backend/database"
+	"backend/env"
+	"backend/utils"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+type attemptResponse struct {
+	Blocks  []string `json:"blocks"`
+	Matches []bool   `json:"matches"`
+	Correct bool     `json:"correct"`
+}
+
+type attemptsSummaryResponse struct {
+	TotalAttempts      int `json:"total_attempts"`
+	CommonWrongAnswers []struct {
+		Blocks []string `json:"blocks"`
+		Count  int      `json:"count"`
+	} `json:"common_wrong_answers"`
+	PositionErrors []struct {
+		Position  int     `json:"position"`
+		ErrorRate float64 `json:"error_rate"`
+	} `json:"position_errors"`
+}
+
+func TestAttempts(t *testing.T) {
+	db := database.CreateFinalTestinationDB(
+		env.DB_USERNAME, env.DB_PASSWORD,
+		env.DB_HOST, env.DB_PORT, env.DB_NAME)
+	db.CreateSchemas()
+
+	app := fiber.New()
+	blocksGroup := app.Group("/blocks")
+	SetUpBlocksRoutes(&blocksGroup, db)
+	playerGroup := app.Group("/player")
+	SetUpPlayerRoutes(&playerGroup, db)
+	adminGroup := app.Group("/admin")
+	SetUpAdminRoutes(&adminGroup, db)
+
+	cookieOf := func(username string) *http.Cookie {
+		resp := utils.MockLogin(t, app, username, "rootroot")
+		return &http.Cookie{Name: constants.AUTH_COOKIE_NAME, Value: readSessionCookies(resp).access}
+	}
+	get := func(route string, cookie *http.Cookie) (*http.Response, []byte) {
+		req := httptest.NewRequest("GET", route, nil)
+		req.AddCookie(cookie)
+		resp, err := app.Test(req, -1) // -1 means no timeout
+		assert.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		return resp, body
+	}
+
+	player := cookieOf("PleaseRunTests")
+	req := httptest.NewRequest("POST", checkEndpoint, bytes.NewBuffer([]byte(incorrect_submissionString)))
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(player)
+	resp, err := app.Test(req, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, 400, resp.StatusCode, "Submit a wrong solution")
+
+	resp, body := get("/player/games/af8e4754-1b84-4fec-bec4-154a3f894b8f/attempts", player)
+	assert.Equal(t, 200, resp.StatusCode, "Get the attempts of the player")
+	var attempts []attemptResponse
+	assert.NoError(t, json.Unmarshal(body, &attempts))
+	if assert.NotEmpty(t, attempts) {
+		last := attempts[len(attempts)-1]
+		assert.False(t, last.Correct)
+		assert.Equal(t, "50%", last.Blocks[4], "The submitted blocks are stored in order")
+		assert.Equal(t, []bool{true, true, true, true, false, true, true}, last.Matches)
+	}
+
+	resp, _ = get("/player/games/not-a-uuid/attempts", player)
+	assert.Equal(t, 400, resp.StatusCode, "An invalid game ID")
+
+	resp, _ = get("/admin/games/af8e4754-1b84-4fec-bec4-154a3f894b8f/attempts", player)
+	assert.Equal(t, 403, resp.StatusCode, "A player cannot see the attempts of everyone")
+
+	admin := cookieOf("admin")
+	resp, body = get("/admin/games/af8e4754-1b84-4fec-bec4-154a3f894b8f/attempts", admin)
+	assert.Equal(t, 200, resp.StatusCode, "An author sees the summary of the attempts")
+	var summary attemptsSummaryResponse
+	assert.NoError(t, json.Unmarshal(body, &summary))
+	assert.GreaterOrEqual(t, summary.TotalAttempts, 1)
+	assert.NotEmpty(t, summary.CommonWrongAnswers)
+	if assert.Equal(t, 7, len(summary.PositionErrors), "There is an error rate for every position") {
+		assert.Equal(t, 4, summary.PositionErrors[4].Position)
+		assert.Greater(t, summary.PositionErrors[4].ErrorRate, 0.0)
+	}
+
+	resp, _ = get("/admin/games/0987afd7-474b-4308-9f2f-447a0995a1ae/attempts", admin)
+	assert.Equal(t, 404, resp.StatusCode, "The summary of a game that does not exist")
+}
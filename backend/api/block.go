@@ -23,8 +23,8 @@ func SetUpBlocksRoutes(router *fiber.Router, database *database.FinalTestination
 	(*router).Post("/:gameId/check-answer",
 		middlewares.CheckValidUUID("gameId"),
 		middlewares.ParseBodyAsJSON[blockAnswer],
-		middlewares.ValidateJWT,
 		middlewares.InjectDB(database),
+		middlewares.ValidateJWT,
 		middlewares.CheckValidPlayer,
 		checkAnswer,
 	)
@@ -42,7 +42,7 @@ func checkAnswer(c *fiber.Ctx) error {
 		})
 	}
 
-	solution, err := functionality.BlocksOfAnswer(db, gameId)
+	checker, err := functionality.GameChecker(db, gameId)
 
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -56,7 +56,23 @@ func checkAnswer(c *fiber.Ctx) error {
 		}
 	}
 
-	correct_indexes, is_all_correct := ArraysMatch(solution, blockAnswer.Blocks)
+	// The answers are not taken while the clock is paused, the time spent solving the level would not count
+	if err := functionality.ClockCheckRunning(db, gameId, playerID); err != nil {
+		if errors.Is(err, functionality.ErrClockPaused) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Couldn't check your answer, please try again later",
+		})
+	}
+
+	correct_indexes, is_all_correct := checker.Check(blockAnswer.Blocks)
+	if _, err := functionality.AttemptCreate(db, gameId, playerID, blockAnswer.Blocks, correct_indexes, is_all_correct); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Couldn't save your attempt, please try again later",
+		})
+	}
+
 	completed, err := functionality.CheckGamePlayerCompleted(db, gameId, playerID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -81,7 +97,12 @@ func checkAnswer(c *fiber.Ctx) error {
 	var multiplier float64
 	if !completed {
 		// TODO: should return also time_slot
-		score, multiplier, err = functionality.PlayerGameCreateMaxScore(db, gameId, playerID, time.Now().Unix())
+		var justCompleted bool
+		score, multiplier, justCompleted, err = functionality.PlayerGameCreateMaxScore(db, gameId, playerID, time.Now().Unix())
+		if errors.Is(err, functionality.ErrClockPaused) {
+			// the clock was paused while the answer was being checked
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+		}
 		if err != nil {
 			//TODO: check for specific errors
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -89,7 +110,10 @@ func checkAnswer(c *fiber.Ctx) error {
 			})
 
 		}
-
+		// a concurrent answer may have completed the level first, and announced it
+		if justCompleted {
+			go publishCompletion(db, gameId, playerID, score)
+		}
 	}
 
 	next_gameID, err := functionality.GameGetByPreviousGame(db, gameId)
@@ -108,22 +132,3 @@ func checkAnswer(c *fiber.Ctx) error {
 		"multiplier":       multiplier,
 	})
 }
-
-func ArraysMatch(solution []string, answer []string) ([]bool, bool) {
-	correct_indexes := make([]bool, len(solution))
-
-	if len(solution) != len(answer) {
-		return correct_indexes, false
-	}
-
-	is_all_correct := true
-
-	for i, v := range answer {
-		correct_indexes[i] = solution[i] == v
-		if !correct_indexes[i] {
-			is_all_correct = false
-		}
-	}
-
-	return correct_indexes, is_all_correct
-}
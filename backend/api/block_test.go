@@ -16,88 +16,6 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
-// Unit testing for sunction TestMatchArrays
-func TestMatchArrays(t *testing.T) {
-	tests := []struct {
-		description     string // description of the test case
-		answer          []string
-		solution        []string
-		expectedMatches []bool
-		expectedResult  bool
-	}{
-		{
-			description: "Non-empty arrays correctly match",
-			answer: []string{
-				"a",
-				"b",
-				"c",
-			},
-			solution: []string{
-				"a",
-				"b",
-				"c",
-			},
-			expectedMatches: []bool{
-				true,
-				true,
-				true,
-			},
-			expectedResult: true,
-		},
-		{
-			description:     "Empty arrays correctly match",
-			answer:          []string{},
-			solution:        []string{},
-			expectedMatches: []bool{},
-			expectedResult:  true,
-		},
-		{
-			description: "Arrays of different lengths do not match",
-			answer: []string{
-				"a",
-				"b",
-			},
-			solution: []string{
-				"a",
-				"b",
-				"c",
-			},
-			expectedMatches: []bool{
-				false,
-				false,
-				false,
-			},
-			expectedResult: false,
-		},
-		{
-			description: "Arrays of same length but different elements do not match",
-			answer: []string{
-				"a",
-				"b",
-				"1",
-			},
-			solution: []string{
-				"a",
-				"b",
-				"c",
-			},
-			expectedMatches: []bool{
-				true,
-				true,
-				false,
-			},
-			expectedResult: false,
-		},
-	}
-
-	for _, test := range tests {
-		matches, result := ArraysMatch(test.solution, test.answer)
-
-		assert.Equalf(t, test.expectedMatches, matches, test.description)
-		assert.Equalf(t, test.expectedResult, result, test.description)
-	}
-}
-
 var getLevel string = "/game/af8e4754-1b84-4fec-bec4-154a3f894b8f"
 
 var checkEndpoint string = "/blocks/af8e4754-1b84-4fec-bec4-154a3f894b8f/check-answer"
@@ -198,6 +116,11 @@ func TestCheckAnswer(t *testing.T) {
 	gameGroup := app.Group("/game")
 	SetUpGameRoutes(&gameGroup, db)
 
+	loginResp := utils.MockLogin(t, app, "admin", "rootroot")
+	cookie := utils.Filter(loginResp.Cookies(), func(c *http.Cookie) bool {
+		return c.Name == constants.AUTH_COOKIE_NAME
+	})[0].Value
+
 	for _, test := range tests {
 		// get the level to create the db entry
 		getReq := httptest.NewRequest("GET", getLevel, bytes.NewBuffer([]byte("")))
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"backend/constants"
+	"backend/database"
+	"backend/database/functionality"
+	"backend/env"
+	"backend/mailer"
+	"backend/utils"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClock(t *testing.T) {
+	db := database.CreateFinalTestinationDB(
+		env.DB_USERNAME, env.DB_PASSWORD,
+		env.DB_HOST, env.DB_PORT, env.DB_NAME)
+	db.CreateSchemas()
+
+	app := fiber.New()
+	gameGroup := app.Group("/game")
+	SetUpGameRoutes(&gameGroup, db)
+	blocksGroup := app.Group("/blocks")
+	SetUpBlocksRoutes(&blocksGroup, db)
+	playerGroup := app.Group("/player")
+	SetUpPlayerRoutes(&playerGroup, db)
+
+	mailer.Current = &mailer.MemoryMailer{}
+
+	// A new player, that has never opened the first level
+	username := fmt.Sprintf("clocktest%d", rand.Intn(1000000))
+	resp := postJSON(t, app, "/player/register", map[string]string{"username": username, "password": "rootroot", "email": username + "@testination.com"})
+	assert.Equal(t, 200, resp.StatusCode, "Register a new player")
+	login := readSessionCookies(utils.MockLogin(t, app, username, "rootroot"))
+
+	readClock := func(method string, route string, expectedCode int, description string) functionality.ClockDTO {
+		resp := sessionRequest(t, app, method, route, login)
+		assert.Equal(t, expectedCode, resp.StatusCode, description)
+
+		var clock functionality.ClockDTO
+		if resp.StatusCode == 200 {
+			body, err := io.ReadAll(resp.Body)
+			assert.NoError(t, err)
+			assert.NoError(t, json.Unmarshal(body, &clock))
+		}
+		return clock
+	}
+
+	readClock("GET", "/game/af8e4754-1b84-4fec-bec4-154a3f894b8f/clock", 404, "The clock of a level that was never opened")
+	readClock("POST", "/game/af8e4754-1b84-4fec-bec4-154a3f894b8f/clock/pause", 404, "Pause a level that was never opened")
+
+	resp = sessionRequest(t, app, "GET", "/game/af8e4754-1b84-4fec-bec4-154a3f894b8f", login)
+	assert.Equal(t, 200, resp.StatusCode, "Open the level")
+
+	clock := readClock("GET", "/game/af8e4754-1b84-4fec-bec4-154a3f894b8f/clock", 200, "Get the clock")
+	assert.True(t, clock.Running, "Opening the level starts the clock")
+	assert.Equal(t, 4, len(clock.Timeslots), "The remaining time is given for every timeslot")
+	assert.Equal(t, "perfect", clock.Timeslots[0].Timeslot)
+	assert.Greater(t, clock.Timeslots[0].RemainingSeconds, int64(0))
+
+	clock = readClock("POST", "/game/af8e4754-1b84-4fec-bec4-154a3f894b8f/clock/pause", 200, "Pause the clock")
+	assert.False(t, clock.Running)
+	clock = readClock("POST", "/game/af8e4754-1b84-4fec-bec4-154a3f894b8f/clock/pause", 200, "Pause the clock again")
+	assert.False(t, clock.Running)
+
+	clock = readClock("POST", "/game/af8e4754-1b84-4fec-bec4-154a3f894b8f/clock/resume", 200, "Resume the clock")
+	assert.True(t, clock.Running)
+
+	resp = sessionRequest(t, app, "POST", "/game/af8e4754-1b84-4fec-bec4-154a3f894b8f/clock/pause", login)
+	assert.Equal(t, 200, resp.StatusCode, "Pause before leaving the level")
+	resp = sessionRequest(t, app, "GET", "/game/af8e4754-1b84-4fec-bec4-154a3f894b8f", login)
+	assert.Equal(t, 200, resp.StatusCode, "Open the level again")
+	clock = readClock("GET", "/game/af8e4754-1b84-4fec-bec4-154a3f894b8f/clock", 200, "Get the clock")
+	assert.True(t, clock.Running, "Opening the level again resumes the clock")
+
+	var answer map[string][]string
+	assert.NoError(t, json.Unmarshal([]byte(correct_submissionString), &answer))
+
+	// The board of a paused level stays visible, the level cannot be played without the clock running
+	readClock("POST", "/game/af8e4754-1b84-4fec-bec4-154a3f894b8f/clock/pause", 200, "Pause the clock")
+	resp = postJSON(t, app, checkEndpoint, answer, &http.Cookie{Name: constants.AUTH_COOKIE_NAME, Value: login.access})
+	assert.Equal(t, 409, resp.StatusCode, "An answer submitted while paused is rejected")
+	req := httptest.NewRequest("PUT", "/game/af8e4754-1b84-4fec-bec4-154a3f894b8f/draft", bytes.NewBufferString(`{"answer":["<iframe",null,null,null,null,null,null]}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(&http.Cookie{Name: constants.AUTH_COOKIE_NAME, Value: login.access})
+	resp, err := app.Test(req, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, 409, resp.StatusCode, "A draft saved while paused is rejected")
+	clock = readClock("GET", "/game/af8e4754-1b84-4fec-bec4-154a3f894b8f/clock", 200, "Get the clock")
+	assert.False(t, clock.Running, "The rejected answer does not complete the level")
+	player, err := functionality.PlayerGetByEmail(db, username+"@testination.com")
+	assert.NoError(t, err)
+	attempts, err := functionality.AttemptGetByPlayer(db, uuid.MustParse("af8e4754-1b84-4fec-bec4-154a3f894b8f"), uuid.MustParse(player.ID))
+	assert.NoError(t, err)
+	assert.Empty(t, attempts, "The rejected answer is not recorded as an attempt")
+
+	readClock("POST", "/game/af8e4754-1b84-4fec-bec4-154a3f894b8f/clock/resume", 200, "Resume the clock")
+	resp = postJSON(t, app, checkEndpoint, answer, &http.Cookie{Name: constants.AUTH_COOKIE_NAME, Value: login.access})
+	assert.Equal(t, 200, resp.StatusCode, "Complete the level")
+
+	clock = readClock("GET", "/game/af8e4754-1b84-4fec-bec4-154a3f894b8f/clock", 200, "Get the clock of a completed level")
+	assert.False(t, clock.Running, "The clock stops when the level is completed")
+	readClock("POST", "/game/af8e4754-1b84-4fec-bec4-154a3f894b8f/clock/resume", 409, "A completed level cannot be resumed")
+}
@@ -0,0 +1,150 @@
+package api
+
+import (
+	"backend/constants"
+	"backend/database"
+	"backend/database/functionality"
+	"backend/env"
+	"backend/mailer"
+	"backend/utils"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCoins(t *testing.T) {
+	db := database.CreateFinalTestinationDB(
+		env.DB_USERNAME, env.DB_PASSWORD,
+		env.DB_HOST, env.DB_PORT, env.DB_NAME)
+	db.CreateSchemas()
+
+	app := fiber.New()
+	gameGroup := app.Group("/game")
+	SetUpGameRoutes(&gameGroup, db)
+	blocksGroup := app.Group("/blocks")
+	SetUpBlocksRoutes(&blocksGroup, db)
+	playerGroup := app.Group("/player")
+	SetUpPlayerRoutes(&playerGroup, db)
+	adminGroup := app.Group("/admin")
+	SetUpAdminRoutes(&adminGroup, db)
+
+	mailer.Current = &mailer.MemoryMailer{}
+
+	// A new player, so that the ledger only has the transactions of this test
+	username := fmt.Sprintf("coinstest%d", rand.Intn(1000000))
+	email := username + "@testination.com"
+	resp := postJSON(t, app, "/player/register", map[string]string{"username": username, "password": "rootroot", "email": email})
+	assert.Equal(t, 200, resp.StatusCode, "Register a new player")
+	login := readSessionCookies(utils.MockLogin(t, app, username, "rootroot"))
+	player, err := functionality.PlayerGetByEmail(db, email)
+	assert.NoError(t, err)
+
+	readHistory := func(description string) functionality.CoinHistoryDTO {
+		resp := sessionRequest(t, app, "GET", "/player/coins/history", login)
+		assert.Equal(t, 200, resp.StatusCode, description)
+
+		var history functionality.CoinHistoryDTO
+		body, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		assert.NoError(t, json.Unmarshal(body, &history))
+		return history
+	}
+
+	history := readHistory("The history of a new player")
+	assert.Equal(t, 0, history.Balance)
+	assert.Empty(t, history.Transactions)
+
+	resp = sessionRequest(t, app, "GET", getLevel, login)
+	assert.Equal(t, 200, resp.StatusCode, "Open the first level")
+	resp = postJSON(t, app, checkEndpoint, json.RawMessage(correct_submissionString), &http.Cookie{Name: constants.AUTH_COOKIE_NAME, Value: login.access})
+	assert.Equal(t, 200, resp.StatusCode, "Complete the first level")
+	var completion struct {
+		Score int `json:"score"`
+	}
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(body, &completion))
+
+	history = readHistory("The history after completing a level")
+	assert.Equal(t, completion.Score, history.Balance, "The score of the level is earned")
+	if assert.Len(t, history.Transactions, 1) {
+		assert.Equal(t, constants.COIN_EARN, history.Transactions[0].Kind)
+		assert.Equal(t, constants.COIN_REASON_LEVEL_COMPLETED, history.Transactions[0].Reason)
+	}
+
+	resp = postJSON(t, app, checkEndpoint, json.RawMessage(correct_submissionString), &http.Cookie{Name: constants.AUTH_COOKIE_NAME, Value: login.access})
+	assert.Equal(t, 200, resp.StatusCode, "Complete the first level again")
+	assert.Len(t, readHistory("The history after replaying a level").Transactions, 1, "A completed level is earned only once")
+
+	admin := &http.Cookie{Name: constants.AUTH_COOKIE_NAME, Value: readSessionCookies(utils.MockLogin(t, app, "admin", "rootroot")).access}
+	grantRoute := "/admin/players/" + player.ID + "/coins"
+
+	tests := []struct {
+		description  string
+		route        string
+		cookie       *http.Cookie
+		body         string
+		expectedCode int
+	}{
+		{
+			description:  "A player cannot give coins",
+			route:        grantRoute,
+			cookie:       &http.Cookie{Name: constants.AUTH_COOKIE_NAME, Value: login.access},
+			body:         `{"kind": "grant", "amount": 50, "reason": "weekly challenge"}`,
+			expectedCode: 403,
+		},
+		{
+			description:  "An admin cannot take coins",
+			route:        grantRoute,
+			cookie:       admin,
+			body:         `{"kind": "spend", "amount": 50, "reason": "weekly challenge"}`,
+			expectedCode: 400,
+		},
+		{
+			description:  "An admin cannot give a negative amount",
+			route:        grantRoute,
+			cookie:       admin,
+			body:         `{"kind": "grant", "amount": -50, "reason": "weekly challenge"}`,
+			expectedCode: 400,
+		},
+		{
+			description:  "An admin cannot give coins to a player that does not exist",
+			route:        "/admin/players/0987afd7-474b-4308-9f2f-447a0995a1ae/coins",
+			cookie:       admin,
+			body:         `{"kind": "grant", "amount": 50, "reason": "weekly challenge"}`,
+			expectedCode: 404,
+		},
+		{
+			description:  "An admin grants coins",
+			route:        grantRoute,
+			cookie:       admin,
+			body:         `{"kind": "grant", "amount": 50, "reason": "weekly challenge"}`,
+			expectedCode: 201,
+		},
+		{
+			description:  "An admin refunds a hint of a level",
+			route:        grantRoute,
+			cookie:       admin,
+			body:         `{"kind": "refund", "amount": 10, "reason": "broken hint", "game_id": "af8e4754-1b84-4fec-bec4-154a3f894b8f"}`,
+			expectedCode: 201,
+		},
+	}
+
+	for _, test := range tests {
+		resp := postJSON(t, app, test.route, json.RawMessage(test.body), test.cookie)
+		assert.Equalf(t, test.expectedCode, resp.StatusCode, test.description)
+	}
+
+	history = readHistory("The history after the grants")
+	assert.Equal(t, completion.Score+60, history.Balance)
+	if assert.Len(t, history.Transactions, 3) {
+		assert.Equal(t, constants.COIN_REFUND, history.Transactions[0].Kind, "The most recent transaction comes first")
+		assert.Equal(t, 10, history.Transactions[0].Amount)
+	}
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"backend/constants"
+	"backend/database"
+	"backend/database/functionality"
+	"backend/events"
+	"backend/loggers"
+	"bufio"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// How often a comment is sent on an idle stream, so that proxies do not close it
+const keepAliveInterval = 15 * time.Second
+
+func SetUpEventRoutes(router *fiber.Router) {
+	(*router).Get("/", streamEvents)
+}
+
+// streamEvents streams the live events as Server-Sent Events until the client disconnects
+func streamEvents(c *fiber.Ctx) error {
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+
+	subscription, unsubscribe := events.Current.Subscribe()
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer unsubscribe()
+		keepAlive := time.NewTicker(keepAliveInterval)
+		defer keepAlive.Stop()
+
+		// the headers are sent with the first flush
+		if _, err := w.WriteString(": connected\n\n"); err != nil || w.Flush() != nil {
+			return
+		}
+		for {
+			select {
+			case event, open := <-subscription:
+				if !open {
+					return
+				}
+				data, err := events.EncodeSSE(event)
+				if err != nil {
+					loggers.Error.Printf("Couldn't encode the %s event: %s", event.Type, err)
+					continue
+				}
+				if _, err := w.Write(data); err != nil {
+					return
+				}
+			case <-keepAlive.C:
+				if _, err := w.WriteString(": keep-alive\n\n"); err != nil {
+					return
+				}
+			}
+			// writing to a client that disconnected fails on flush
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	})
+	return nil
+}
+
+// publishCompletion announces a level completed by a player, whether it was the first solve of the level
+// and how the `score` it earned changed the all-time ranks. It runs after the answer was recorded, off the
+// request, so the errors are only logged.
+func publishCompletion(db *database.FinalTestinationDB, gameID uuid.UUID, playerID uuid.UUID, score int) {
+	completion, err := functionality.LevelCompletionGet(db, gameID, playerID)
+	if err != nil {
+		loggers.Error.Printf("Couldn't get the completion of %s by %s: %s", gameID, playerID, err)
+		return
+	}
+	publish(constants.LIVE_EVENT_LEVEL_COMPLETED, completion)
+	if completion.Position == 1 {
+		publish(constants.LIVE_EVENT_FIRST_SOLVE, completion)
+	}
+
+	changes, err := functionality.LeaderboardRankChanges(db, playerID, score)
+	if err != nil {
+		loggers.Error.Printf("Couldn't get the rank changes of %s: %s", playerID, err)
+		return
+	}
+	// a single event, so that a client that falls behind does not miss part of the changes
+	if len(changes) > 0 {
+		publish(constants.LIVE_EVENT_RANK_CHANGED, changes)
+	}
+}
+
+func publish(eventType string, data any) {
+	if err := events.Publish(eventType, data); err != nil {
+		loggers.Error.Printf("Couldn't publish the %s event: %s", eventType, err)
+	}
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"backend/constants"
+	"backend/database"
+	"backend/database/functionality"
+	"backend/env"
+	"backend/events"
+	"backend/mailer"
+	"backend/utils"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLiveEvents(t *testing.T) {
+	db := database.CreateFinalTestinationDB(
+		env.DB_USERNAME, env.DB_PASSWORD,
+		env.DB_HOST, env.DB_PORT, env.DB_NAME)
+	db.CreateSchemas()
+
+	app := fiber.New()
+	gameGroup := app.Group("/game")
+	SetUpGameRoutes(&gameGroup, db)
+	blocksGroup := app.Group("/blocks")
+	SetUpBlocksRoutes(&blocksGroup, db)
+	playerGroup := app.Group("/player")
+	SetUpPlayerRoutes(&playerGroup, db)
+
+	mailer.Current = &mailer.MemoryMailer{}
+	events.Current = events.NewMemoryHub()
+	subscription, unsubscribe := events.Current.Subscribe()
+	defer unsubscribe()
+
+	username := fmt.Sprintf("livetest%d", rand.Intn(1000000))
+	resp := postJSON(t, app, "/player/register", map[string]string{"username": username, "password": "rootroot", "email": username + "@testination.com"})
+	assert.Equal(t, 200, resp.StatusCode, "Register a new player")
+	login := readSessionCookies(utils.MockLogin(t, app, username, "rootroot"))
+	cookie := &http.Cookie{Name: constants.AUTH_COOKIE_NAME, Value: login.access}
+
+	assert.Equal(t, 200, sessionRequest(t, app, "GET", getLevel, login).StatusCode, "Open the first level")
+	assert.Equal(t, 400, postJSON(t, app, checkEndpoint, json.RawMessage(incorrect_submissionString), cookie).StatusCode, "A wrong answer")
+	assert.Empty(t, subscription, "A wrong answer is not announced")
+
+	// A rival with a single coin, that the player overtakes by completing the level
+	rival := username + "rival"
+	resp = postJSON(t, app, "/player/register", map[string]string{"username": rival, "password": "rootroot", "email": rival + "@testination.com"})
+	assert.Equal(t, 200, resp.StatusCode, "Register the rival")
+	rivalLogin := readSessionCookies(utils.MockLogin(t, app, rival, "rootroot"))
+	assert.Equal(t, 200, sessionRequest(t, app, "GET", getLevel, rivalLogin).StatusCode, "The rival opens the first level")
+	rivalPlayer, err := functionality.PlayerGetByEmail(db, rival+"@testination.com")
+	assert.NoError(t, err)
+	_, err = functionality.CoinGrant(db, uuid.MustParse(rivalPlayer.ID), constants.COIN_GRANT, 1, "live events test", nil, nil)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 200, postJSON(t, app, checkEndpoint, json.RawMessage(correct_submissionString), cookie).StatusCode, "Complete the first level")
+
+	// The events are published after the response, the rank changes last
+	received := map[string]events.Event{}
+	announced := func(eventType string) bool {
+		_, ok := received[eventType]
+		return ok
+	}
+	timeout := time.After(5 * time.Second)
+	for !announced(constants.LIVE_EVENT_RANK_CHANGED) {
+		select {
+		case event := <-subscription:
+			received[event.Type] = event
+		case <-timeout:
+			t.Fatal("The rank change was not announced")
+		}
+	}
+
+	if assert.True(t, announced(constants.LIVE_EVENT_LEVEL_COMPLETED)) {
+		completion := received[constants.LIVE_EVENT_LEVEL_COMPLETED].Data.(*functionality.LevelCompletion)
+		assert.Equal(t, username, completion.Username)
+		assert.Greater(t, completion.Score, 0)
+		assert.Equal(t, completion.Position == 1, announced(constants.LIVE_EVENT_FIRST_SOLVE), "Only the first player to complete a level solves it first")
+	}
+	changes := received[constants.LIVE_EVENT_RANK_CHANGED].Data.([]functionality.RankChange)
+	if assert.NotEmpty(t, changes) {
+		assert.Equal(t, username, changes[0].Username, "The rank of the player comes first")
+		assert.Less(t, changes[0].Rank, changes[0].PreviousRank, "The player climbs the leaderboard")
+	}
+	overtaken := utils.Filter(changes, func(change functionality.RankChange) bool { return change.Username == rival })
+	if assert.Len(t, overtaken, 1, "The players that were overtaken are announced") {
+		assert.Equal(t, overtaken[0].PreviousRank+1, overtaken[0].Rank, "The rival moves down by one")
+		assert.Equal(t, 1, overtaken[0].Score)
+	}
+
+	assert.Equal(t, 200, postJSON(t, app, checkEndpoint, json.RawMessage(correct_submissionString), cookie).StatusCode, "Replay the first level")
+	time.Sleep(100 * time.Millisecond)
+	assert.Empty(t, subscription, "Replaying a level is not announced")
+}
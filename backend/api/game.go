@@ -1,12 +1,14 @@
 package api
 
 import (
+	"backend/constants"
 	"backend/database"
 	"backend/database/entity"
 	"backend/database/functionality"
 	"backend/middlewares"
 	"fmt"
 	"math/rand"
+	"slices"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
@@ -21,6 +23,10 @@ type hintUsedRequest struct {
 	Order    *int   `json:"order"`
 }
 
+type draftRequest struct {
+	Answer []*string `json:"answer"`
+}
+
 func SetUpGameRoutes(router *fiber.Router, database *database.FinalTestinationDB) {
 	(*router).Get("/:gameId",
 		middlewares.CheckValidUUID("gameId"),
@@ -38,6 +44,78 @@ func SetUpGameRoutes(router *fiber.Router, database *database.FinalTestinationDB
 		middlewares.CheckValidPlayer,
 		useHint,
 	)
+
+	(*router).Put("/:gameId/draft",
+		middlewares.CheckValidUUID("gameId"),
+		middlewares.ParseBodyAsJSON[draftRequest],
+		middlewares.InjectDB(database),
+		middlewares.ValidateJWT,
+		middlewares.CheckValidPlayer,
+		saveDraft,
+	)
+
+	(*router).Get("/:gameId/clock",
+		middlewares.CheckValidUUID("gameId"),
+		middlewares.InjectDB(database),
+		middlewares.ValidateJWT,
+		middlewares.CheckValidPlayer,
+		getClock,
+	)
+	(*router).Post("/:gameId/clock/pause",
+		middlewares.CheckValidUUID("gameId"),
+		middlewares.InjectDB(database),
+		middlewares.ValidateJWT,
+		middlewares.CheckValidPlayer,
+		pauseClock,
+	)
+	(*router).Post("/:gameId/clock/resume",
+		middlewares.CheckValidUUID("gameId"),
+		middlewares.InjectDB(database),
+		middlewares.ValidateJWT,
+		middlewares.CheckValidPlayer,
+		resumeClock,
+	)
+}
+
+// clockResponse sends the state of the clock, or maps the error of the clock functionalities to an HTTP response
+func clockResponse(c *fiber.Ctx, clock *functionality.ClockDTO, err error) error {
+	switch {
+	case err == nil:
+		return c.JSON(clock)
+	case errors.Is(err, functionality.ErrClockNotStarted):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Open the level to start its clock"})
+	case errors.Is(err, functionality.ErrClockStopped):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Couldn't get the clock of the level"})
+	}
+}
+
+func getClock(c *fiber.Ctx) error {
+	gameId := c.Locals("gameId").(uuid.UUID)
+	db := c.Locals("db").(*database.FinalTestinationDB)
+	player := c.Locals("player").(entity.Player)
+
+	clock, err := functionality.ClockGet(db, gameId, uuid.MustParse(player.ID))
+	return clockResponse(c, clock, err)
+}
+
+func pauseClock(c *fiber.Ctx) error {
+	gameId := c.Locals("gameId").(uuid.UUID)
+	db := c.Locals("db").(*database.FinalTestinationDB)
+	player := c.Locals("player").(entity.Player)
+
+	clock, err := functionality.ClockPause(db, gameId, uuid.MustParse(player.ID))
+	return clockResponse(c, clock, err)
+}
+
+func resumeClock(c *fiber.Ctx) error {
+	gameId := c.Locals("gameId").(uuid.UUID)
+	db := c.Locals("db").(*database.FinalTestinationDB)
+	player := c.Locals("player").(entity.Player)
+
+	clock, err := functionality.ClockResume(db, gameId, uuid.MustParse(player.ID))
+	return clockResponse(c, clock, err)
 }
 
 func getGame(c *fiber.Ctx) error {
@@ -87,25 +165,52 @@ func getGame(c *fiber.Ctx) error {
 		})
 	}
 
+	hints, spent, err := functionality.PlayerGameHints(db, gameId, playerID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Couldn't get the hints used by the player",
+		})
+	}
+
+	// the price of the next textual hint, 0 when every hint was revealed
+	textualHints := functionality.TextualHintsOf(game.TextualHints, hints)
+	textualHintPrice := 0
+	if next := functionality.NextTextualHint(game.TextualHints, hints); next < len(game.TextualHints) {
+		textualHintPrice = game.TextualHints[next].Price
+	}
+
 	// if the user has already completed the level, signal to the frontend that the user can always
 	// buy the hints, and that the hints are free
 	if pg.EndTime != nil {
-		pg.TimeFreezePointsUsed = 0
-		pg.TextualHintPointsUsed = 0
-		pg.HintSolutionPointsUsed = 0
+		spent = functionality.HintsSpent{FilledBlocks: []int{}}
 		game.TimeFreezePrice = 0
-		game.TextualHintPrice = 0
+		textualHintPrice = 0
+		for i := range textualHints {
+			textualHints[i].Price = 0
+		}
 		game.HintSolutionPrice = 0
+		game.EliminateDecoysPrice = 0
 	}
 
 	//TODO: should probably unit-test this part
 	skeleton := fiber.Map{}
 	blocks := []string{}
 	solutionLength := 0
+	decoysLeft := 0
+	// the content of the blocks revealed by the fill hints, by position
+	filled := fiber.Map{}
 
 	for _, block := range game.Blocks {
 		if block.Order != nil {
 			solutionLength++
+			if slices.Contains(spent.FilledBlocks, int(*block.Order)) {
+				filled[fmt.Sprintf("%d", *block.Order)] = block.Content
+			}
+		} else if slices.Contains(pg.EliminatedBlocks, block.ID) {
+			// removed by an eliminate hint
+			continue
+		} else {
+			decoysLeft++
 		}
 
 		if block.Skeleton {
@@ -117,27 +222,57 @@ func getGame(c *fiber.Ctx) error {
 	}
 
 	body := fiber.Map{
-		"title":                game.Title,
-		"story":                game.Story,
-		"cheatsheet":           game.Cheatsheet,
-		"skeleton":             skeleton,
-		"blocks":               arrayShuffle(blocks),
-		"solution_length":      solutionLength,
-		"background":           game.Background,
-		"winning_message":      game.WinningMessage,
-		"player_coins":         totalCoins,
-		"freeze_time_duration": game.TimeFreezeDuration,
-		"freeze_time_price":    game.TimeFreezePrice,
-		"freeze_time_used":     pg.TimeFreezePointsUsed,
-		"textual_hint_price":   game.TextualHintPrice,
-		"textual_hint_used":    pg.TextualHintPointsUsed,
-		"solution_hint_price":  game.HintSolutionPrice,
-		"solution_hint_used":   pg.HintSolutionPointsUsed,
+		"title":                  game.Title,
+		"story":                  game.Story,
+		"cheatsheet":             game.Cheatsheet,
+		"skeleton":               skeleton,
+		"blocks":                 arrayShuffle(blocks),
+		"solution_length":        solutionLength,
+		"background":             game.Background,
+		"winning_message":        game.WinningMessage,
+		"player_coins":           totalCoins,
+		"freeze_time_duration":   game.TimeFreezeDuration,
+		"freeze_time_price":      game.TimeFreezePrice,
+		"freeze_time_used":       spent.Freeze,
+		"textual_hint_price":     textualHintPrice,
+		"textual_hint_used":      spent.Textual,
+		"textual_hints":          textualHints,
+		"solution_hint_price":    game.HintSolutionPrice,
+		"solution_hint_used":     spent.Fill,
+		"filled_blocks":          spent.FilledBlocks,
+		"filled_block_contents":  filled,
+		"draft":                  functionality.PlayerGameDraft(game, pg),
+		"eliminate_decoys_count": game.EliminateDecoysCount,
+		"eliminate_decoys_price": game.EliminateDecoysPrice,
+		"eliminate_decoys_used":  spent.Eliminate,
+		"decoys_left":            decoysLeft,
+		"hints":                  hints,
 	}
 
 	return c.JSON(body)
 }
 
+func saveDraft(c *fiber.Ctx) error {
+	answer := c.Locals("parsedBody").(draftRequest).Answer
+	gameId := c.Locals("gameId").(uuid.UUID)
+	db := c.Locals("db").(*database.FinalTestinationDB)
+	player := c.Locals("player").(entity.Player)
+
+	draft, err := functionality.PlayerGameSaveDraft(db, gameId, uuid.MustParse(player.ID), answer)
+	switch {
+	case err == nil:
+		return c.JSON(draft)
+	case errors.Is(err, functionality.ErrClockNotStarted):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Open the level to save its draft"})
+	case errors.Is(err, functionality.ErrClockStopped), errors.Is(err, functionality.ErrClockPaused):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, functionality.ErrInvalidDraft):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Couldn't save the draft"})
+	}
+}
+
 // TODO: probably should be done in a better way
 func arrayShuffle[T any](a []T) []T {
 	for i := range a {
@@ -149,14 +284,14 @@ func arrayShuffle[T any](a []T) []T {
 
 func useHint(c *fiber.Ctx) error {
 	hintType := c.Locals("parsedBody").(hintUsedRequest).HintType
-	if hintType != "freeze" && hintType != "textual" && hintType != "fill" {
+	if hintType != constants.HINT_FREEZE && hintType != constants.HINT_TEXTUAL && hintType != constants.HINT_FILL && hintType != constants.HINT_ELIMINATE {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "Invalid hint type",
 		})
 	}
 
 	var order *int
-	if hintType == "fill" {
+	if hintType == constants.HINT_FILL {
 		order = c.Locals("parsedBody").(hintUsedRequest).Order
 		if order == nil {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -171,14 +306,7 @@ func useHint(c *fiber.Ctx) error {
 
 	playerID := uuid.MustParse(player.ID)
 
-	totalPoints, err := functionality.PlayerGetTotalCoins(db, playerID)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Coulnd't get the number of coins for the current user",
-		})
-	}
-
-	statusCode, hintContent, err := functionality.PlayerGameUseHint(db, gameId, playerID, hintType, order, totalPoints)
+	statusCode, hintContent, err := functionality.PlayerGameUseHint(db, gameId, playerID, hintType, order, c.Get("Idempotency-Key"))
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return c.Status(statusCode).JSON(fiber.Map{
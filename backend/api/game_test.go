@@ -3,18 +3,25 @@ package api
 import (
 	"backend/constants"
 	"backend/database"
+	"backend/database/entity"
+	"backend/database/functionality"
 	"backend/env"
+	"backend/mailer"
 	"backend/utils"
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"io"
+	"math/rand"
 	"net/http"
 	"net/http/httptest"
 	"slices"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -180,7 +187,7 @@ func TestUseHint(t *testing.T) {
 			route:                "/game/05732286-9fa5-45d4-bef3-13ae0d481afa/hint",
 			expectedCode:         403,
 			body:                 `{"hint_type":"textual"}`,
-			expectedHintResponse: `{"error":"cannot buy hint with type textual a second time"}`,
+			expectedHintResponse: `{"error":"every textual hint was already revealed"}`,
 		},
 		{
 			method:               "POST",
@@ -264,3 +271,293 @@ func TestUseHint(t *testing.T) {
 		}
 	}
 }
+
+func TestUseHintConcurrently(t *testing.T) {
+	db := database.CreateFinalTestinationDB(
+		env.DB_USERNAME, env.DB_PASSWORD,
+		env.DB_HOST, env.DB_PORT, env.DB_NAME)
+	db.CreateSchemas()
+
+	app := fiber.New()
+	gameGroup := app.Group("/game")
+	SetUpGameRoutes(&gameGroup, db)
+	blocksGroup := app.Group("/blocks")
+	SetUpBlocksRoutes(&blocksGroup, db)
+	playerGroup := app.Group("/player")
+	SetUpPlayerRoutes(&playerGroup, db)
+
+	mailer.Current = &mailer.MemoryMailer{}
+
+	// A new player that completes the first level, so that it has some coins to buy the hints of the second one
+	username := fmt.Sprintf("hinttest%d", rand.Intn(1000000))
+	email := username + "@testination.com"
+	resp := postJSON(t, app, "/player/register", map[string]string{"username": username, "password": "rootroot", "email": email})
+	assert.Equal(t, 200, resp.StatusCode, "Register a new player")
+	login := readSessionCookies(utils.MockLogin(t, app, username, "rootroot"))
+	cookie := &http.Cookie{Name: constants.AUTH_COOKIE_NAME, Value: login.access}
+	player, err := functionality.PlayerGetByEmail(db, email)
+	assert.NoError(t, err)
+	playerID := uuid.MustParse(player.ID)
+
+	assert.Equal(t, 200, sessionRequest(t, app, "GET", getLevel, login).StatusCode, "Open the first level")
+	assert.Equal(t, 200, postJSON(t, app, checkEndpoint, json.RawMessage(correct_submissionString), cookie).StatusCode, "Complete the first level")
+	assert.Equal(t, 200, sessionRequest(t, app, "GET", "/game/05732286-9fa5-45d4-bef3-13ae0d481afa", login).StatusCode, "Open the second level")
+
+	game, err := functionality.GameGetById(db, uuid.MustParse("05732286-9fa5-45d4-bef3-13ae0d481afa"))
+	assert.NoError(t, err)
+	_, err = functionality.CoinGrant(db, playerID, constants.COIN_GRANT, game.TextualHints[0].Price+game.TimeFreezePrice, "hint test", nil, nil)
+	assert.NoError(t, err)
+	before, err := functionality.PlayerGetTotalCoins(db, playerID)
+	assert.NoError(t, err)
+
+	// buyConcurrently sends the same purchase `n` times in parallel and returns the status codes and the bodies
+	buyConcurrently := func(n int, body string, idempotencyKey string) ([]int, []string) {
+		codes := make([]int, n)
+		bodies := make([]string, n)
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				req := httptest.NewRequest("POST", "/game/05732286-9fa5-45d4-bef3-13ae0d481afa/hint", bytes.NewBuffer([]byte(body)))
+				req.Header.Set("Content-Type", "application/json")
+				if idempotencyKey != "" {
+					req.Header.Set("Idempotency-Key", idempotencyKey)
+				}
+				req.AddCookie(cookie)
+				resp, err := app.Test(req, -1) // -1 means no timeout
+				assert.NoError(t, err)
+				responseBody, err := io.ReadAll(resp.Body)
+				assert.NoError(t, err)
+				codes[i] = resp.StatusCode
+				bodies[i] = string(responseBody)
+			}(i)
+		}
+		wg.Wait()
+		return codes, bodies
+	}
+
+	countCodes := func(codes []int, code int) int {
+		return len(utils.Filter(codes, func(c int) bool { return c == code }))
+	}
+
+	codes, _ := buyConcurrently(10, `{"hint_type":"textual"}`, "")
+	assert.Equal(t, 1, countCodes(codes, 200), "Only one of the parallel purchases buys the hint")
+	assert.Equal(t, 9, countCodes(codes, 403), "The other purchases find the hint already bought")
+	coins, err := functionality.PlayerGetTotalCoins(db, playerID)
+	assert.NoError(t, err)
+	assert.Equal(t, before-game.TextualHints[0].Price, coins, "The hint is paid once")
+
+	key := uuid.New().String()
+	codes, bodies := buyConcurrently(10, `{"hint_type":"freeze"}`, key)
+	assert.Equal(t, 10, countCodes(codes, 200), "Every retry of the purchase succeeds")
+	for _, body := range bodies {
+		assert.Equal(t, bodies[0], body, "Every retry gets the same hint")
+	}
+	coins, err = functionality.PlayerGetTotalCoins(db, playerID)
+	assert.NoError(t, err)
+	assert.Equal(t, before-game.TextualHints[0].Price-game.TimeFreezePrice, coins, "The retries are paid once")
+
+	codes, _ = buyConcurrently(1, `{"hint_type":"fill","order":1}`, key)
+	assert.Equal(t, []int{422}, codes, "The idempotency key cannot be used for another purchase")
+
+	// Every block of the solution can be filled once
+	orders := []int{}
+	for _, block := range game.Blocks {
+		if block.Order != nil && !block.Skeleton {
+			orders = append(orders, int(*block.Order))
+		}
+	}
+	slices.Sort(orders)
+	_, err = functionality.CoinGrant(db, playerID, constants.COIN_GRANT, 2*game.HintSolutionPrice, "hint test", nil, nil)
+	assert.NoError(t, err)
+
+	codes, _ = buyConcurrently(1, fmt.Sprintf(`{"hint_type":"fill","order":%d}`, orders[0]), "")
+	assert.Equal(t, []int{200}, codes, "Fill a block")
+	codes, _ = buyConcurrently(1, fmt.Sprintf(`{"hint_type":"fill","order":%d}`, orders[0]), "")
+	assert.Equal(t, []int{403}, codes, "Fill the same block again")
+	codes, _ = buyConcurrently(1, fmt.Sprintf(`{"hint_type":"fill","order":%d}`, orders[1]), "")
+	assert.Equal(t, []int{200}, codes, "Fill another block")
+
+	resp = sessionRequest(t, app, "GET", "/game/05732286-9fa5-45d4-bef3-13ae0d481afa", login)
+	assert.Equal(t, 200, resp.StatusCode, "Open the second level again")
+	var level struct {
+		TextualHintUsed  *int                           `json:"textual_hint_used"`
+		SolutionHintUsed *int                           `json:"solution_hint_used"`
+		FilledBlocks     []int                          `json:"filled_blocks"`
+		TextualHints     []functionality.TextualHintDTO `json:"textual_hints"`
+		Hints            []struct {
+			HintType string `json:"hint_type"`
+			Price    int    `json:"price"`
+		} `json:"hints"`
+	}
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(body, &level))
+	if assert.NotNil(t, level.TextualHintUsed) {
+		assert.Equal(t, game.TextualHints[0].Price, *level.TextualHintUsed, "The price paid for the textual hint")
+	}
+	if assert.NotNil(t, level.SolutionHintUsed) {
+		assert.Equal(t, 2*game.HintSolutionPrice, *level.SolutionHintUsed, "Every filled block is paid")
+	}
+	assert.Equal(t, orders[:2], level.FilledBlocks)
+	if assert.Len(t, level.TextualHints, len(game.TextualHints), "Every textual hint is listed") {
+		assert.Equal(t, &game.TextualHints[0].Content, level.TextualHints[0].Content, "The textual hint bought is revealed")
+	}
+	assert.Len(t, level.Hints, 4, "Every purchase is recorded")
+}
+
+func TestEliminateHint(t *testing.T) {
+	db := database.CreateFinalTestinationDB(
+		env.DB_USERNAME, env.DB_PASSWORD,
+		env.DB_HOST, env.DB_PORT, env.DB_NAME)
+	db.CreateSchemas()
+
+	app := fiber.New()
+	gameGroup := app.Group("/game")
+	SetUpGameRoutes(&gameGroup, db)
+	playerGroup := app.Group("/player")
+	SetUpPlayerRoutes(&playerGroup, db)
+
+	mailer.Current = &mailer.MemoryMailer{}
+
+	// The first level has 7 decoys, removed 4 at a time
+	gameID := "af8e4754-1b84-4fec-bec4-154a3f894b8f"
+	assert.NoError(t, db.Orm.Model(&entity.Game{}).Where("id = ?", gameID).
+		Updates(map[string]any{"eliminate_decoys_count": 4, "eliminate_decoys_price": 10}).Error)
+	defer db.Orm.Model(&entity.Game{}).Where("id = ?", gameID).
+		Updates(map[string]any{"eliminate_decoys_count": 0, "eliminate_decoys_price": 0})
+
+	username := fmt.Sprintf("eliminatetest%d", rand.Intn(1000000))
+	email := username + "@testination.com"
+	resp := postJSON(t, app, "/player/register", map[string]string{"username": username, "password": "rootroot", "email": email})
+	assert.Equal(t, 200, resp.StatusCode, "Register a new player")
+	login := readSessionCookies(utils.MockLogin(t, app, username, "rootroot"))
+	cookie := &http.Cookie{Name: constants.AUTH_COOKIE_NAME, Value: login.access}
+	player, err := functionality.PlayerGetByEmail(db, email)
+	assert.NoError(t, err)
+	_, err = functionality.CoinGrant(db, uuid.MustParse(player.ID), constants.COIN_GRANT, 20, "hint test", nil, nil)
+	assert.NoError(t, err)
+
+	type level struct {
+		Blocks              []string `json:"blocks"`
+		DecoysLeft          int      `json:"decoys_left"`
+		EliminateDecoysUsed *int     `json:"eliminate_decoys_used"`
+	}
+	openLevel := func(description string) level {
+		resp := sessionRequest(t, app, "GET", "/game/"+gameID, login)
+		assert.Equal(t, 200, resp.StatusCode, description)
+		body, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		var parsed level
+		assert.NoError(t, json.Unmarshal(body, &parsed))
+		return parsed
+	}
+	eliminate := func() (int, []string) {
+		resp := postJSON(t, app, "/game/"+gameID+"/hint", map[string]string{"hint_type": constants.HINT_ELIMINATE}, cookie)
+		body, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		var hint expectedHintResponse
+		assert.NoError(t, json.Unmarshal(body, &hint))
+		removed := []string{}
+		if resp.StatusCode == 200 {
+			assert.NoError(t, json.Unmarshal([]byte(hint.HintContent), &removed))
+		}
+		return resp.StatusCode, removed
+	}
+
+	before := openLevel("Open the level")
+	assert.Equal(t, 7, before.DecoysLeft)
+	assert.Nil(t, before.EliminateDecoysUsed)
+
+	code, removed := eliminate()
+	assert.Equal(t, 200, code, "Eliminate some decoys")
+	assert.Len(t, removed, 4)
+
+	after := openLevel("Open the level again")
+	assert.Equal(t, 3, after.DecoysLeft, "The decoys stay eliminated")
+	assert.Len(t, after.Blocks, len(before.Blocks)-4)
+	for _, content := range removed {
+		assert.NotContains(t, after.Blocks, content, "An eliminated decoy is not shown")
+	}
+	if assert.NotNil(t, after.EliminateDecoysUsed) {
+		assert.Equal(t, 10, *after.EliminateDecoysUsed)
+	}
+
+	code, removed = eliminate()
+	assert.Equal(t, 200, code, "Eliminate the last decoys")
+	assert.Len(t, removed, 3)
+	assert.Equal(t, 0, openLevel("Open the level without decoys").DecoysLeft)
+
+	code, _ = eliminate()
+	assert.Equal(t, 403, code, "There are no decoys left to eliminate")
+
+	coins, err := functionality.PlayerGetTotalCoins(db, uuid.MustParse(player.ID))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, coins, "Every elimination is paid")
+}
+
+func TestDraft(t *testing.T) {
+	db := database.CreateFinalTestinationDB(
+		env.DB_USERNAME, env.DB_PASSWORD,
+		env.DB_HOST, env.DB_PORT, env.DB_NAME)
+	db.CreateSchemas()
+
+	app := fiber.New()
+	gameGroup := app.Group("/game")
+	SetUpGameRoutes(&gameGroup, db)
+	blocksGroup := app.Group("/blocks")
+	SetUpBlocksRoutes(&blocksGroup, db)
+	playerGroup := app.Group("/player")
+	SetUpPlayerRoutes(&playerGroup, db)
+
+	mailer.Current = &mailer.MemoryMailer{}
+
+	username := fmt.Sprintf("drafttest%d", rand.Intn(1000000))
+	email := username + "@testination.com"
+	resp := postJSON(t, app, "/player/register", map[string]string{"username": username, "password": "rootroot", "email": email})
+	assert.Equal(t, 200, resp.StatusCode, "Register a new player")
+	login := readSessionCookies(utils.MockLogin(t, app, username, "rootroot"))
+	cookie := &http.Cookie{Name: constants.AUTH_COOKIE_NAME, Value: login.access}
+
+	putDraft := func(route string, body string) *http.Response {
+		req := httptest.NewRequest("PUT", route, bytes.NewBuffer([]byte(body)))
+		req.Header.Set("Content-Type", "application/json")
+		req.AddCookie(cookie)
+		resp, err := app.Test(req, -1) // -1 means no timeout
+		assert.NoError(t, err)
+		return resp
+	}
+	type level struct {
+		Draft *struct {
+			Answer []*string `json:"answer"`
+		} `json:"draft"`
+	}
+	openLevel := func(description string) level {
+		resp := sessionRequest(t, app, "GET", getLevel, login)
+		assert.Equal(t, 200, resp.StatusCode, description)
+		body, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		var parsed level
+		assert.NoError(t, json.Unmarshal(body, &parsed))
+		return parsed
+	}
+
+	assert.Equal(t, 404, putDraft(getLevel+"/draft", `{"answer":[null,null,null,null,null,null,null]}`).StatusCode, "Save the draft of a level never opened")
+	assert.Nil(t, openLevel("Open the level").Draft, "There is no draft yet")
+
+	assert.Equal(t, 200, putDraft(getLevel+"/draft", `{"answer":["<iframe",null,"evilcompany",null,null,null,null]}`).StatusCode, "Save a draft")
+	draft := openLevel("Open the level on another device").Draft
+	if assert.NotNil(t, draft, "The draft is returned with the level") && assert.Len(t, draft.Answer, 7) {
+		assert.Equal(t, "<iframe", *draft.Answer[0])
+		assert.Equal(t, "evilcompany", *draft.Answer[2])
+		assert.Nil(t, draft.Answer[1])
+	}
+
+	assert.Equal(t, 400, putDraft(getLevel+"/draft", `{"answer":["<iframe"]}`).StatusCode, "A draft with the wrong number of positions")
+	assert.Equal(t, 400, putDraft(getLevel+"/draft", `{"answer":["UNION",null,null,null,null,null,null]}`).StatusCode, "A draft with a block of another level")
+
+	assert.Equal(t, 200, postJSON(t, app, checkEndpoint, json.RawMessage(correct_submissionString), cookie).StatusCode, "Complete the level")
+	assert.Equal(t, 409, putDraft(getLevel+"/draft", `{"answer":[null,null,null,null,null,null,null]}`).StatusCode, "Save the draft of a completed level")
+	assert.Nil(t, openLevel("Open the completed level").Draft, "The draft of a completed level is not returned")
+}
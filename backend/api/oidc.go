@@ -0,0 +1,118 @@
+package api
+
+import (
+	"backend/database"
+	"backend/database/entity"
+	"backend/database/functionality"
+	"backend/oidc"
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type idTokenRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+var errUnknownProvider = errors.New("unknown login provider")
+
+// verifyIdentity checks the ID token of the body with the given provider
+func verifyIdentity(c *fiber.Ctx, providerName string) (*functionality.ExternalIdentity, error) {
+	body := c.Locals("parsedBody").(idTokenRequest)
+
+	provider, ok := oidc.Providers[providerName]
+	if !ok {
+		return nil, errUnknownProvider
+	}
+
+	claims, err := provider.Verify(body.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	emailName, _, _ := strings.Cut(claims.Email, "@")
+	return &functionality.ExternalIdentity{
+		Provider:      provider.Name,
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: bool(claims.EmailVerified),
+		Usernames:     []string{claims.PreferredUsername, claims.Name, emailName},
+	}, nil
+}
+
+// identityErrorResponse maps the errors of the OpenID Connect login to an HTTP response
+func identityErrorResponse(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, errUnknownProvider):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Unknown login provider"})
+	case errors.Is(err, oidc.ErrInvalidToken):
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token"})
+	case errors.Is(err, functionality.ErrIdentityNoEmail):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, functionality.ErrIdentityEmailTaken), errors.Is(err, functionality.ErrIdentityTaken):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, oidc.ErrProviderUnavailable):
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": "Couldn't reach the login provider"})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not retrieve user"})
+	}
+}
+
+func loginWith(c *fiber.Ctx, providerName string) error {
+	db := c.Locals("db").(*database.FinalTestinationDB)
+
+	identity, err := verifyIdentity(c, providerName)
+	if err != nil {
+		return identityErrorResponse(c, err)
+	}
+
+	user, err := functionality.PlayerLoginWithIdentity(db, *identity)
+	if err != nil {
+		return identityErrorResponse(c, err)
+	}
+
+	if err := startSession(c, db, user); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "could not generate token",
+		})
+	}
+
+	return c.JSON(user)
+}
+
+func loginWithProvider(c *fiber.Ctx) error {
+	return loginWith(c, c.Params("provider"))
+}
+
+func loginWithGoogle(c *fiber.Ctx) error {
+	return loginWith(c, "google")
+}
+
+func linkProvider(c *fiber.Ctx) error {
+	db := c.Locals("db").(*database.FinalTestinationDB)
+	player := c.Locals("player").(entity.Player)
+
+	identity, err := verifyIdentity(c, c.Params("provider"))
+	if err != nil {
+		return identityErrorResponse(c, err)
+	}
+
+	if err := functionality.PlayerLinkIdentity(db, player.ID, *identity); err != nil {
+		return identityErrorResponse(c, err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func getIdentities(c *fiber.Ctx) error {
+	db := c.Locals("db").(*database.FinalTestinationDB)
+	player := c.Locals("player").(entity.Player)
+
+	identities, err := functionality.PlayerGetIdentities(db, player.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Couldn't get the linked accounts"})
+	}
+
+	return c.JSON(identities)
+}
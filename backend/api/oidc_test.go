@@ -0,0 +1,150 @@
+package api
+
+import (
+	"backend/constants"
+	"backend/database"
+	"backend/database/entity"
+	"backend/database/functionality"
+	"backend/env"
+	"backend/mailer"
+	"backend/oidc"
+	"backend/utils"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	mathrand "math/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// newStubIssuer starts a local OpenID Connect issuer with a single RSA key, registered as the `stub` provider
+func newStubIssuer(t *testing.T) (*httptest.Server, *rsa.PrivateKey) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	var server *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"issuer": server.URL, "jwks_uri": server.URL + "/keys"})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "stub",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	oidc.Providers["stub"] = oidc.NewProvider("stub", server.URL, "client-id")
+	return server, key
+}
+
+func stubIDToken(t *testing.T, server *httptest.Server, key *rsa.PrivateKey, subject string, email string, verified bool) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":                server.URL,
+		"aud":                "client-id",
+		"sub":                subject,
+		"email":              email,
+		"email_verified":     verified,
+		"preferred_username": "stub.player",
+		"exp":                time.Now().Add(time.Hour).Unix(),
+	})
+	token.Header["kid"] = "stub"
+	signed, err := token.SignedString(key)
+	assert.NoError(t, err)
+	return signed
+}
+
+func TestOIDCLogin(t *testing.T) {
+	db := database.CreateFinalTestinationDB(
+		env.DB_USERNAME, env.DB_PASSWORD,
+		env.DB_HOST, env.DB_PORT, env.DB_NAME)
+	db.CreateSchemas()
+
+	app := fiber.New()
+	playerGroup := app.Group("/player")
+	SetUpPlayerRoutes(&playerGroup, db)
+
+	server, key := newStubIssuer(t)
+	mailer.Current = &mailer.MemoryMailer{}
+
+	type player struct {
+		ID            string `json:"ID"`
+		Username      string `json:"username"`
+		EmailVerified bool   `json:"emailVerified"`
+	}
+	loginAs := func(description string, token string, expectedCode int) player {
+		resp := postJSON(t, app, "/player/oidc/stub/login", map[string]string{"token": token})
+		assert.Equal(t, expectedCode, resp.StatusCode, description)
+		body, _ := io.ReadAll(resp.Body)
+		var result player
+		json.Unmarshal(body, &result)
+		return result
+	}
+
+	subject := uuid.New().String()
+	newEmail := subject + "@testination.com"
+
+	created := loginAs("A new identity creates a player", stubIDToken(t, server, key, subject, newEmail, true), 200)
+	assert.NotEmpty(t, created.ID)
+	assert.Contains(t, created.Username, "stubplayer", "The username is derived from the provider")
+	assert.True(t, created.EmailVerified, "The provider verified the email")
+
+	again := loginAs("The same identity logs in the same player", stubIDToken(t, server, key, subject, newEmail, true), 200)
+	assert.Equal(t, created.ID, again.ID)
+
+	// A player registered with an email it did not verify, maybe by someone that does not own it
+	username := fmt.Sprintf("oidctest%d", mathrand.Intn(1000000))
+	email := username + "@testination.com"
+	resp := postJSON(t, app, "/player/register", map[string]string{"username": username, "password": "rootroot", "email": email})
+	assert.Equal(t, 200, resp.StatusCode, "Register a player")
+	loginAs("A verified email is not linked to a player that did not verify it",
+		stubIDToken(t, server, key, uuid.New().String(), email, true), 409)
+	registered, err := functionality.PlayerGetByEmail(db, email)
+	assert.NoError(t, err)
+	assert.False(t, registered.EmailVerified, "The email of the player is still unverified")
+
+	assert.NoError(t, db.Orm.Model(&entity.Player{}).Where("id = ?", registered.ID).Update("email_verified", true).Error)
+	linked := loginAs("A verified email links the identity to the existing player",
+		stubIDToken(t, server, key, uuid.New().String(), email, true), 200)
+	assert.Equal(t, registered.ID, linked.ID)
+	assert.Equal(t, username, linked.Username, "The username of an existing player is not changed")
+
+	loginAs("An unverified email of an existing player is not linked",
+		stubIDToken(t, server, key, uuid.New().String(), "test@testination.com", false), 409)
+
+	loginAs("A tampered token is rejected",
+		stubIDToken(t, server, key, subject, newEmail, true)+"tampered", 401)
+
+	resp = postJSON(t, app, "/player/oidc/unknown/login", map[string]string{"token": "token"})
+	assert.Equal(t, 404, resp.StatusCode, "An unknown provider")
+
+	// A logged in player links the identity explicitly
+	linkSubject := uuid.New().String()
+	linkToken := stubIDToken(t, server, key, linkSubject, "someone.else@testination.com", false)
+	login := readSessionCookies(utils.MockLogin(t, app, "test", "rootroot"))
+	resp = postJSON(t, app, "/player/oidc/stub/link", map[string]string{"token": linkToken},
+		&http.Cookie{Name: constants.AUTH_COOKIE_NAME, Value: login.access})
+	assert.Equal(t, 204, resp.StatusCode, "Link an identity")
+
+	resp = postJSON(t, app, "/player/oidc/stub/link", map[string]string{"token": stubIDToken(t, server, key, subject, newEmail, true)},
+		&http.Cookie{Name: constants.AUTH_COOKIE_NAME, Value: login.access})
+	assert.Equal(t, 409, resp.StatusCode, "An identity linked to another player cannot be linked")
+
+	linkedTest := loginAs("The linked identity logs in the player", linkToken, 200)
+	assert.Equal(t, "6d4c437b-5803-4b08-890b-44383af74ab3", linkedTest.ID)
+}
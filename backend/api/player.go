@@ -5,15 +5,13 @@ import (
 	"backend/database"
 	"backend/database/entity"
 	"backend/database/functionality"
-	"backend/jwt"
+	"backend/loggers"
 	"backend/middlewares"
-	"encoding/json"
-	"fmt"
-	"net/http"
 	"strings"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 )
 
 type userCredentials struct {
@@ -21,10 +19,6 @@ type userCredentials struct {
 	Password   string `binding:"required" validate:"testination-password"`
 }
 
-type googleLoginRequest struct {
-	Token string `json:"token" binding:"required"`
-}
-
 func (u userCredentials) Validate(v *validator.Validate) error {
 	return v.Struct(u)
 }
@@ -50,9 +44,22 @@ func SetUpPlayerRoutes(router *fiber.Router, database *database.FinalTestination
 	(*router).Get("/availableLevels", middlewares.InjectDB(database), middlewares.ValidateJWT, middlewares.CheckValidPlayer, seeAvailableLevels)
 	(*router).Get("/profile", middlewares.InjectDB(database), middlewares.ValidateJWT, middlewares.CheckValidPlayer, getProfile)
 	(*router).Get("/availableIcons", middlewares.InjectDB(database), middlewares.ValidateJWT, middlewares.CheckValidPlayer, getAvailableIcons)
+	(*router).Get("/coins/history", middlewares.InjectDB(database), middlewares.ValidateJWT, middlewares.CheckValidPlayer, getCoinHistory)
+	(*router).Get("/games/:gameId/attempts", middlewares.CheckValidUUID("gameId"), middlewares.InjectDB(database), middlewares.ValidateJWT, middlewares.CheckValidPlayer, getAttempts)
 	(*router).Post("/changeIcon", middlewares.ParseBodyAsJSON[icon], middlewares.InjectDB(database), middlewares.ValidateJWT, middlewares.CheckValidPlayer, changeIcon)
-	(*router).Post("/logout", logOut)
-	(*router).Post("/googleLogin", middlewares.ParseBodyAsJSON[googleLoginRequest], middlewares.InjectDB(database), loginWithGoogle) // new endpoint for Google login
+	(*router).Post("/refresh", middlewares.InjectDB(database), refreshSession)
+	(*router).Get("/sessions", middlewares.InjectDB(database), middlewares.ValidateJWT, middlewares.CheckValidPlayer, getSessions)
+	(*router).Delete("/sessions", middlewares.InjectDB(database), middlewares.ValidateJWT, middlewares.CheckValidPlayer, revokeAllSessions)
+	(*router).Delete("/sessions/:sessionId", middlewares.InjectDB(database), middlewares.ValidateJWT, middlewares.CheckValidPlayer, revokeSession)
+	(*router).Post("/logout", middlewares.InjectDB(database), logOut)
+	(*router).Post("/forgot-password", middlewares.ParseBodyAsJSON[forgotPasswordRequest], middlewares.InjectDB(database), forgotPassword)
+	(*router).Post("/reset-password", middlewares.ParseBodyAsJSON[resetPasswordRequest], middlewares.InjectDB(database), resetPassword)
+	(*router).Post("/verify-email", middlewares.ParseBodyAsJSON[tokenRequest], middlewares.InjectDB(database), verifyEmail)
+	(*router).Post("/resend-verification", middlewares.InjectDB(database), middlewares.ValidateJWT, middlewares.CheckValidPlayer, resendVerificationEmail)
+	(*router).Post("/oidc/:provider/login", middlewares.ParseBodyAsJSON[idTokenRequest], middlewares.InjectDB(database), loginWithProvider)
+	(*router).Post("/oidc/:provider/link", middlewares.ParseBodyAsJSON[idTokenRequest], middlewares.InjectDB(database), middlewares.ValidateJWT, middlewares.CheckValidPlayer, linkProvider)
+	(*router).Get("/identities", middlewares.InjectDB(database), middlewares.ValidateJWT, middlewares.CheckValidPlayer, getIdentities)
+	(*router).Post("/googleLogin", middlewares.ParseBodyAsJSON[idTokenRequest], middlewares.InjectDB(database), loginWithGoogle) // Kept for the existing frontend
 }
 
 func register(c *fiber.Ctx) error {
@@ -65,6 +72,11 @@ func register(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"Impossible to create a new user": err.Error()})
 	}
 
+	// The account works even if the email could not be sent, the player can ask for a new one
+	if err := sendVerificationEmail(db, user); err != nil {
+		loggers.Error.Printf("Couldn't send the verification email to %s: %s", user.Email, err)
+	}
+
 	return c.JSON(user)
 }
 
@@ -101,142 +113,65 @@ func login(c *fiber.Ctx) error {
 		})
 	}
 
-	token, err := jwt.GenerateJWT(user.ID)
-
-	if err != nil {
+	if err := startSession(c, db, user); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "could not generate token",
 		})
 	}
 
-	const JWT_EXPIRATION int = 60 * 60 * 24 * 31
-
-	c.Cookie(&fiber.Cookie{
-		Name:     constants.AUTH_COOKIE_NAME,
-		Value:    token,
-		MaxAge:   JWT_EXPIRATION,
-		SameSite: "None",
-		Secure:   true,
-		HTTPOnly: true,
-	})
-
 	return c.JSON(user)
 }
 
-// Function for Google login
-func loginWithGoogle(c *fiber.Ctx) error {
-	fmt.Println("Request received at /googleLogin")
-	db := c.Locals("db").(*database.FinalTestinationDB)
-	body := c.Locals("parsedBody").(googleLoginRequest) // body.Token contains the Google token
-
-	// 1. Google token verification
-	googleAPIURL := "https://oauth2.googleapis.com/tokeninfo?id_token=" + body.Token
-	resp, err := http.Get(googleAPIURL)
-	if err != nil || resp.StatusCode != http.StatusOK {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Invalid Google token",
-		})
-	}
-	defer resp.Body.Close()
-
-	// Decode the JSON response
-	var googleData struct {
-		Iss           string `json:"iss"`            // Issuer
-		Aud           string `json:"aud"`            // Audience
-		Email         string `json:"email"`          // Email
-		EmailVerified string `json:"email_verified"` // "true" or "false" as string
-		Name          string `json:"name"`           // Full name
-		Picture       string `json:"picture"`        // Profile picture URL
-		Exp           string `json:"exp"`            // Expiration time
-		Iat           string `json:"iat"`            // Issued at time
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&googleData); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to parse Google API response",
-		})
-	}
+func getLoggedInfo(c *fiber.Ctx) error {
+	player := c.Locals("parsedBody").(*entity.Player)
 
-	// Verify the token is for your Client ID
-	if googleData.Aud != "Your Client ID" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Token audience mismatch",
-		})
-	}
+	return c.JSON(player)
+}
 
-	// Check if the email is verified
-	if googleData.EmailVerified == "false" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Google email not verified",
-		})
+func getProfile(c *fiber.Ctx) error {
+	db := c.Locals("db").(*database.FinalTestinationDB)
+	player := c.Locals("player").(entity.Player)
+	result, err := functionality.Profile(db, &player)
+	if err != nil {
+		// TODO: diversify errors
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	// Use the data to authenticate or register the user
-	fmt.Printf("Email: %s, Name: %s\n", googleData.Email, googleData.Name)
+	return c.JSON(result)
+}
 
-	// 2. Search for the user in the database
-	user, err := functionality.PlayerGetByEmail(db, googleData.Email)
+// getCoinHistory returns a page of the coin ledger of the player, `?page=` starts from 1
+func getCoinHistory(c *fiber.Ctx) error {
+	db := c.Locals("db").(*database.FinalTestinationDB)
+	player := c.Locals("player").(entity.Player)
+	playerID, err := uuid.Parse(player.ID)
 	if err != nil {
-		// Handle database errors
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "could not retrieve user",
-		})
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Invalid player ID"})
 	}
 
-	if user == nil {
-		// User not found, create it
-		user, err = functionality.PlayerCreate(db, googleData.Name, "", googleData.Email)
-		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "could not create user",
-			})
-		}
-	} else {
-		// User found, update data if necessary
-		user.Username = googleData.Name
-		if err := functionality.PlayerUpdate(db, user); err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "could not update user",
-			})
-		}
-	}
-
-	// 3. Generate a JWT token
-	token, err := jwt.GenerateJWT(user.ID)
+	history, err := functionality.CoinHistory(db, playerID, c.QueryInt("page", 1))
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "could not generate token",
-		})
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Couldn't get the coin history"})
 	}
 
-	const JWT_EXPIRATION int = 60 * 60 * 24 * 31
-	c.Cookie(&fiber.Cookie{
-		Name:     constants.AUTH_COOKIE_NAME,
-		Value:    token,
-		MaxAge:   JWT_EXPIRATION,
-		SameSite: "None",
-		Secure:   true,
-		HTTPOnly: true,
-	})
-
-	return c.JSON(user)
-}
-
-func getLoggedInfo(c *fiber.Ctx) error {
-	player := c.Locals("parsedBody").(*entity.Player)
-
-	return c.JSON(player)
+	return c.JSON(history)
 }
 
-func getProfile(c *fiber.Ctx) error {
+func getAttempts(c *fiber.Ctx) error {
+	gameId := c.Locals("gameId").(uuid.UUID)
 	db := c.Locals("db").(*database.FinalTestinationDB)
 	player := c.Locals("player").(entity.Player)
-	result, err := functionality.Profile(db, &player)
+	playerID, err := uuid.Parse(player.ID)
 	if err != nil {
-		// TODO: diversify errors
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Invalid player ID"})
 	}
 
-	return c.JSON(result)
+	attempts, err := functionality.AttemptGetByPlayer(db, gameId, playerID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Couldn't get your attempts"})
+	}
+
+	return c.JSON(attempts)
 }
 
 func getAvailableIcons(c *fiber.Ctx) error {
@@ -261,22 +196,15 @@ func changeIcon(c *fiber.Ctx) error {
 	return c.SendStatus(fiber.StatusOK)
 }
 func logOut(c *fiber.Ctx) error {
-	fmt.Println("Logout endpoint called")
-
-	// Clear the cookie
-	c.ClearCookie(constants.AUTH_COOKIE_NAME)
-
-	// Explicitly set a cookie with the same properties but with expiration in the past
-	c.Cookie(&fiber.Cookie{
-		Name:     constants.AUTH_COOKIE_NAME,
-		Value:    "",
-		MaxAge:   -1, // Set to a negative value
-		SameSite: "None",
-		Secure:   true, // Keep true in production
-		HTTPOnly: true,
-		Path:     "/", // Match the cookie path to ensure deletion
-	})
-
-	fmt.Println("Cookie cleared")
+	db := c.Locals("db").(*database.FinalTestinationDB)
+
+	// Revoke the session, so that the tokens are useless even if they were stolen
+	if refreshToken := c.Cookies(constants.REFRESH_COOKIE_NAME); refreshToken != "" {
+		if err := functionality.SessionRevokeByRefreshToken(db, refreshToken); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Couldn't revoke the session"})
+		}
+	}
+
+	clearSessionCookies(c)
 	return nil
 }
@@ -3,44 +3,131 @@ package api
 import (
 	"backend/constants"
 	"backend/database"
+	"backend/database/entity"
 	"backend/database/functionality"
 	"backend/middlewares"
-	"math"
+	"errors"
+	"fmt"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 func SetUpPlayerGameRoutes(router *fiber.Router, database *database.FinalTestinationDB) {
+	(*router).Get("/games/:gameId/:page?",
+		middlewares.CheckValidUUID("gameId"),
+		middlewares.CheckValidPageNumber("page"),
+		middlewares.InjectDB(database),
+		getGameLeaderboard,
+	)
+	(*router).Get("/me",
+		middlewares.InjectDB(database),
+		middlewares.ValidateJWT,
+		middlewares.CheckValidPlayer,
+		getLeaderboardPosition,
+	)
 	(*router).Get("/:page", middlewares.CheckValidPageNumber("page"), middlewares.InjectDB(database), getPlayersData)
 }
 
+// leaderboardOptions reads the query parameters shared by the leaderboards: the `window` (all-time by default),
+// the `ranking` of the ties (competition by default) and the `page_size`
+func leaderboardOptions(c *fiber.Ctx) (functionality.LeaderboardOptions, error) {
+	options := functionality.LeaderboardOptions{
+		Ranking:  c.Query("ranking", constants.RANKING_COMPETITION),
+		PageSize: c.QueryInt("page_size", constants.PAGE_SIZE),
+	}
+
+	since, err := functionality.LeaderboardWindowStart(c.Query("window", constants.LEADERBOARD_WINDOW_ALL), time.Now())
+	if err != nil {
+		return options, err
+	}
+	options.Since = since
+
+	if options.Ranking != constants.RANKING_COMPETITION && options.Ranking != constants.RANKING_DENSE {
+		return options, fmt.Errorf("the ranking must be %s or %s", constants.RANKING_COMPETITION, constants.RANKING_DENSE)
+	}
+	if options.PageSize < 1 || options.PageSize > constants.MAX_PAGE_SIZE {
+		return options, fmt.Errorf("the page size must be between 1 and %d", constants.MAX_PAGE_SIZE)
+	}
+	return options, nil
+}
+
+// leaderboardError maps the errors of a leaderboard page to an HTTP response
+func leaderboardError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, functionality.ErrInvalidLeaderboardCursor) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"queryError": "An error occurred while fetching the leaderboard data" + err.Error(),
+	})
+}
+
 func getPlayersData(c *fiber.Ctx) error {
 	page := c.Locals("page").(int)
 	db := c.Locals("db").(*database.FinalTestinationDB)
 
-	elementCount, err := functionality.GetLeaderbordElementsNumber(db)
+	options, err := leaderboardOptions(c)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"queryError": "An error occurred while fetching the number of pages" + err.Error(),
-		})
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	maxPageNumber := int(math.Ceil(float64(elementCount) / constants.PAGE_SIZE))
+	res, err := functionality.GetLeaderboardPlayers(db, page, c.Query("cursor"), options)
+	if err != nil {
+		return leaderboardError(c, err)
+	}
+	return c.JSON(res)
+}
 
-	if page > maxPageNumber {
-		page = maxPageNumber
+func getGameLeaderboard(c *fiber.Ctx) error {
+	page := c.Locals("page").(int)
+	gameId := c.Locals("gameId").(uuid.UUID)
+	db := c.Locals("db").(*database.FinalTestinationDB)
+
+	options, err := leaderboardOptions(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if _, err := functionality.GameGetById(db, gameId); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Couldn't find the game you're looking for"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Couldn't get the game you're looking for"})
 	}
 
-	res, err := functionality.GetLeaderboardPlayers(db, (page - 1))
+	res, err := functionality.GetGameLeaderboard(db, gameId, page, c.Query("cursor"), options)
 	if err != nil {
+		return leaderboardError(c, err)
+	}
+	return c.JSON(res)
+}
+
+func getLeaderboardPosition(c *fiber.Ctx) error {
+	db := c.Locals("db").(*database.FinalTestinationDB)
+	player := c.Locals("player").(entity.Player)
+
+	options, err := leaderboardOptions(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	// how many players to list above and below the player
+	around := c.QueryInt("around", 5)
+	if around < 0 || around > constants.PAGE_SIZE {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"queryError": "An error occurred while fetching the leaderboard data" + err.Error(),
+			"error": fmt.Sprintf("around must be between 0 and %d", constants.PAGE_SIZE),
 		})
 	}
 
-	return c.JSON(fiber.Map{
-		"currentPage": page,
-		"pages":       maxPageNumber,
-		"entries":     res,
-	})
+	position, err := functionality.GetLeaderboardPosition(db, uuid.MustParse(player.ID), around, options)
+	if err != nil {
+		if errors.Is(err, functionality.ErrNotInLeaderboard) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Play a level to enter the leaderboard"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Couldn't get your position in the leaderboard"})
+	}
+
+	return c.JSON(position)
 }
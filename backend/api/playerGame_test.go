@@ -1,15 +1,25 @@
 package api
 
 import (
+	"backend/constants"
 	"backend/database"
+	"backend/database/entity"
+	"backend/database/functionality"
 	"backend/env"
+	"backend/mailer"
+	"backend/utils"
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"io"
+	"math/rand"
+	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -90,3 +100,285 @@ func TestLeaderBoardNavigation(t *testing.T) {
 		assert.Equalf(t, test.expectedMaxPage, parsedResponseBody.Pages, test.description)
 	}
 }
+
+type expectedGameLeaderboardResponse struct {
+	CurrentPage int `json:"currentPage"`
+	Pages       int `json:"pages"`
+	Entries     []struct {
+		Username     string `json:"username"`
+		Score        int    `json:"score"`
+		SolveSeconds *int64 `json:"solve_seconds"`
+	} `json:"entries"`
+}
+
+func TestLeaderboardWindows(t *testing.T) {
+	db := database.CreateFinalTestinationDB(
+		env.DB_USERNAME, env.DB_PASSWORD,
+		env.DB_HOST, env.DB_PORT, env.DB_NAME)
+	db.CreateSchemas()
+
+	app := fiber.New()
+	gameGroup := app.Group("/leaderboard")
+	SetUpPlayerGameRoutes(&gameGroup, db)
+
+	get := func(route string) (*http.Response, []byte) {
+		resp, err := app.Test(httptest.NewRequest("GET", route, nil), -1) // -1 means no timeout
+		assert.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		return resp, body
+	}
+
+	resp, body := get("/leaderboard/games/af8e4754-1b84-4fec-bec4-154a3f894b8f")
+	assert.Equal(t, 200, resp.StatusCode, "The leaderboard of a level")
+	var level expectedGameLeaderboardResponse
+	assert.NoError(t, json.Unmarshal(body, &level))
+	assert.NotEmpty(t, level.Entries, "Some players completed the first level")
+	for i := 1; i < len(level.Entries); i++ {
+		assert.GreaterOrEqual(t, level.Entries[i-1].Score, level.Entries[i].Score, "The completions are ranked by score")
+	}
+
+	resp, body = get("/leaderboard/games/af8e4754-1b84-4fec-bec4-154a3f894b8f/1?window=week")
+	assert.Equal(t, 200, resp.StatusCode, "The weekly leaderboard of a level")
+	var weekly expectedGameLeaderboardResponse
+	assert.NoError(t, json.Unmarshal(body, &weekly))
+	assert.LessOrEqual(t, len(weekly.Entries), len(level.Entries), "The weekly leaderboard only has the recent completions")
+
+	resp, body = get("/leaderboard/1?window=month")
+	assert.Equal(t, 200, resp.StatusCode, "The monthly leaderboard")
+	var monthly expectedLeaderBoardResponse
+	assert.NoError(t, json.Unmarshal(body, &monthly))
+	assert.LessOrEqual(t, monthly.Pages, 2)
+
+	resp, _ = get("/leaderboard/1?window=year")
+	assert.Equal(t, 400, resp.StatusCode, "An unknown window")
+
+	resp, _ = get("/leaderboard/games/0987afd7-474b-4308-9f2f-447a0995a1ae")
+	assert.Equal(t, 404, resp.StatusCode, "The leaderboard of a level that does not exist")
+}
+
+type expectedRankedEntry struct {
+	Rank     int    `json:"rank"`
+	Username string `json:"username"`
+	Score    int    `json:"score"`
+	Me       bool   `json:"me"`
+}
+
+type expectedLeaderboardPositionResponse struct {
+	Rank       int                   `json:"rank"`
+	Score      int                   `json:"score"`
+	Percentile float64               `json:"percentile"`
+	Players    int                   `json:"players"`
+	Entries    []expectedRankedEntry `json:"entries"`
+}
+
+func TestLeaderboardPosition(t *testing.T) {
+	db := database.CreateFinalTestinationDB(
+		env.DB_USERNAME, env.DB_PASSWORD,
+		env.DB_HOST, env.DB_PORT, env.DB_NAME)
+	db.CreateSchemas()
+
+	app := fiber.New()
+	leaderboardGroup := app.Group("/leaderboard")
+	SetUpPlayerGameRoutes(&leaderboardGroup, db)
+	gameGroup := app.Group("/game")
+	SetUpGameRoutes(&gameGroup, db)
+	blocksGroup := app.Group("/blocks")
+	SetUpBlocksRoutes(&blocksGroup, db)
+	playerGroup := app.Group("/player")
+	SetUpPlayerRoutes(&playerGroup, db)
+
+	mailer.Current = &mailer.MemoryMailer{}
+
+	username := fmt.Sprintf("ranktest%d", rand.Intn(1000000))
+	resp := postJSON(t, app, "/player/register", map[string]string{"username": username, "password": "rootroot", "email": username + "@testination.com"})
+	assert.Equal(t, 200, resp.StatusCode, "Register a new player")
+	login := readSessionCookies(utils.MockLogin(t, app, username, "rootroot"))
+	cookie := &http.Cookie{Name: constants.AUTH_COOKIE_NAME, Value: login.access}
+
+	resp = sessionRequest(t, app, "GET", "/leaderboard/me", login)
+	assert.Equal(t, 404, resp.StatusCode, "A player that never played is not in the leaderboard")
+
+	assert.Equal(t, 200, sessionRequest(t, app, "GET", getLevel, login).StatusCode, "Open the first level")
+	assert.Equal(t, 200, postJSON(t, app, checkEndpoint, json.RawMessage(correct_submissionString), cookie).StatusCode, "Complete the first level")
+
+	resp = sessionRequest(t, app, "GET", "/leaderboard/me?around=2", login)
+	assert.Equal(t, 200, resp.StatusCode, "Where the player ranks")
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	var position expectedLeaderboardPositionResponse
+	assert.NoError(t, json.Unmarshal(body, &position))
+	assert.GreaterOrEqual(t, position.Rank, 1)
+	assert.LessOrEqual(t, position.Rank, position.Players)
+	assert.Greater(t, position.Score, 0, "The player earned the score of the level")
+	assert.True(t, position.Percentile >= 0 && position.Percentile <= 100, "The percentile is a percentage")
+	assert.LessOrEqual(t, len(position.Entries), 5, "At most two players above and two below")
+	me := utils.Filter(position.Entries, func(entry expectedRankedEntry) bool { return entry.Me })
+	if assert.Len(t, me, 1, "The player is among the entries") {
+		assert.Equal(t, username, me[0].Username)
+		assert.Equal(t, position.Rank, me[0].Rank)
+	}
+	for i := 1; i < len(position.Entries); i++ {
+		assert.LessOrEqual(t, position.Entries[i-1].Rank, position.Entries[i].Rank, "The entries are in order")
+		if position.Entries[i-1].Score == position.Entries[i].Score {
+			assert.Equal(t, position.Entries[i-1].Rank, position.Entries[i].Rank, "The players with the same score share the rank")
+		}
+	}
+
+	resp = sessionRequest(t, app, "GET", "/leaderboard/me?around=100", login)
+	assert.Equal(t, 400, resp.StatusCode, "Too many players around")
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/leaderboard/me", nil), -1) // -1 means no timeout
+	assert.NoError(t, err)
+	assert.Equal(t, 401, resp.StatusCode, "Only a logged in player has a position")
+}
+
+type expectedTiesResponse struct {
+	CurrentPage int                   `json:"currentPage"`
+	Pages       int                   `json:"pages"`
+	Entries     []expectedRankedEntry `json:"entries"`
+	NextCursor  *string               `json:"next_cursor"`
+}
+
+func TestLeaderboardTies(t *testing.T) {
+	db := database.CreateFinalTestinationDB(
+		env.DB_USERNAME, env.DB_PASSWORD,
+		env.DB_HOST, env.DB_PORT, env.DB_NAME)
+	db.CreateSchemas()
+
+	app := fiber.New()
+	leaderboardGroup := app.Group("/leaderboard")
+	SetUpPlayerGameRoutes(&leaderboardGroup, db)
+	gameGroup := app.Group("/game")
+	SetUpGameRoutes(&gameGroup, db)
+	playerGroup := app.Group("/player")
+	SetUpPlayerRoutes(&playerGroup, db)
+
+	mailer.Current = &mailer.MemoryMailer{}
+
+	get := func(route string) (*http.Response, expectedTiesResponse) {
+		resp, err := app.Test(httptest.NewRequest("GET", route, nil), -1) // -1 means no timeout
+		assert.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		var parsed expectedTiesResponse
+		if resp.StatusCode == 200 {
+			assert.NoError(t, json.Unmarshal(body, &parsed))
+		}
+		return resp, parsed
+	}
+
+	// Three new players at the top of the leaderboard, the first two with the same score
+	top := int(time.Now().Unix()) * 10
+	usernames := []string{}
+	for i, amount := range []int{top, top, top - 1} {
+		username := fmt.Sprintf("tietest%dn%d", rand.Intn(1000000), i)
+		email := username + "@testination.com"
+		resp := postJSON(t, app, "/player/register", map[string]string{"username": username, "password": "rootroot", "email": email})
+		assert.Equal(t, 200, resp.StatusCode, "Register a new player")
+		login := readSessionCookies(utils.MockLogin(t, app, username, "rootroot"))
+		assert.Equal(t, 200, sessionRequest(t, app, "GET", getLevel, login).StatusCode, "Open the first level")
+		player, err := functionality.PlayerGetByEmail(db, email)
+		assert.NoError(t, err)
+		_, err = functionality.CoinGrant(db, uuid.MustParse(player.ID), constants.COIN_GRANT, amount, "leaderboard test", nil, nil)
+		assert.NoError(t, err)
+		usernames = append(usernames, username)
+	}
+
+	resp, board := get("/leaderboard/1?page_size=3")
+	assert.Equal(t, 200, resp.StatusCode, "The top of the leaderboard")
+	assert.Equal(t, usernames, utils.Map(board.Entries, func(e expectedRankedEntry) string { return e.Username }), "Whoever reached the score first comes first")
+	assert.Equal(t, []int{1, 1, 3}, utils.Map(board.Entries, func(e expectedRankedEntry) int { return e.Rank }), "Competition ranking skips the ranks after a tie")
+
+	_, dense := get("/leaderboard/1?page_size=3&ranking=dense")
+	assert.Equal(t, []int{1, 1, 2}, utils.Map(dense.Entries, func(e expectedRankedEntry) int { return e.Rank }), "Dense ranking does not skip ranks")
+
+	_, first := get("/leaderboard/1?page_size=2")
+	if assert.NotNil(t, first.NextCursor, "There is a page after the first") {
+		_, next := get("/leaderboard/1?page_size=2&cursor=" + *first.NextCursor)
+		if assert.NotEmpty(t, next.Entries) {
+			assert.Equal(t, usernames[2], next.Entries[0].Username, "The next page starts right after the cursor")
+			assert.Equal(t, 3, next.Entries[0].Rank)
+			assert.Equal(t, 2, next.CurrentPage, "The page of the first entry after the cursor")
+		}
+	}
+	_, second := get("/leaderboard/2?page_size=2")
+	if assert.NotEmpty(t, second.Entries) {
+		assert.Equal(t, usernames[2], second.Entries[0].Username, "Consecutive pages do not overlap")
+	}
+
+	resp, _ = get("/leaderboard/1?cursor=invalid")
+	assert.Equal(t, 400, resp.StatusCode, "An invalid cursor")
+	resp, _ = get("/leaderboard/1?page_size=0")
+	assert.Equal(t, 400, resp.StatusCode, "An invalid page size")
+	resp, _ = get("/leaderboard/1?ranking=olympic")
+	assert.Equal(t, 400, resp.StatusCode, "An unknown ranking")
+
+	// A level nobody completed has an empty leaderboard
+	game := entity.Game{Title: "Empty leaderboard", GameOrder: 2000 + rand.Intn(1000000)}
+	assert.NoError(t, functionality.GameCreate(db, &game))
+	defer functionality.GameDelete(db, uuid.MustParse(game.ID))
+	resp, empty := get("/leaderboard/games/" + game.ID + "/3")
+	assert.Equal(t, 200, resp.StatusCode, "The leaderboard of a level nobody completed")
+	assert.Equal(t, 1, empty.CurrentPage, "An empty leaderboard has a first page")
+	assert.Equal(t, 1, empty.Pages)
+	assert.Empty(t, empty.Entries)
+	assert.Nil(t, empty.NextCursor)
+}
+
+func TestLeaderboardStandings(t *testing.T) {
+	db := database.CreateFinalTestinationDB(
+		env.DB_USERNAME, env.DB_PASSWORD,
+		env.DB_HOST, env.DB_PORT, env.DB_NAME)
+	db.CreateSchemas()
+
+	app := fiber.New()
+	leaderboardGroup := app.Group("/leaderboard")
+	SetUpPlayerGameRoutes(&leaderboardGroup, db)
+	gameGroup := app.Group("/game")
+	SetUpGameRoutes(&gameGroup, db)
+	playerGroup := app.Group("/player")
+	SetUpPlayerRoutes(&playerGroup, db)
+
+	mailer.Current = &mailer.MemoryMailer{}
+
+	username := fmt.Sprintf("standingstest%d", rand.Intn(1000000))
+	email := username + "@testination.com"
+	resp := postJSON(t, app, "/player/register", map[string]string{"username": username, "password": "rootroot", "email": email})
+	assert.Equal(t, 200, resp.StatusCode, "Register a new player")
+	login := readSessionCookies(utils.MockLogin(t, app, username, "rootroot"))
+	player, err := functionality.PlayerGetByEmail(db, email)
+	assert.NoError(t, err)
+	playerID := uuid.MustParse(player.ID)
+
+	score := func() int {
+		resp := sessionRequest(t, app, "GET", "/leaderboard/me?around=0", login)
+		assert.Equal(t, 200, resp.StatusCode, "Where the player ranks")
+		body, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		var position expectedLeaderboardPositionResponse
+		assert.NoError(t, json.Unmarshal(body, &position))
+		return position.Score
+	}
+
+	_, err = functionality.CoinGrant(db, playerID, constants.COIN_GRANT, 70, "standings test", nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 404, sessionRequest(t, app, "GET", "/leaderboard/me", login).StatusCode, "A player that never played is not in the standings")
+
+	assert.Equal(t, 200, sessionRequest(t, app, "GET", getLevel, login).StatusCode, "Open the first level")
+	assert.Equal(t, 70, score(), "The coins granted before playing count")
+
+	_, err = functionality.CoinGrant(db, playerID, constants.COIN_REFUND, 30, "standings test", nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 100, score(), "Every transaction updates the standings")
+
+	assert.NoError(t, db.Orm.Exec("UPDATE player_standings SET score = 0 WHERE player_id = ?", player.ID).Error)
+	assert.Equal(t, 0, score(), "The leaderboard reads the standings")
+
+	players, err := functionality.StandingsRebuild(db)
+	assert.NoError(t, err)
+	assert.Greater(t, players, 0)
+	total, err := functionality.PlayerGetTotalCoins(db, playerID)
+	assert.NoError(t, err)
+	assert.Equal(t, total, score(), "The rebuild computes the standings from the ledger")
+}
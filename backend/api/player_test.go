@@ -281,7 +281,7 @@ func TestGetProfile(t *testing.T) {
 		assert.Equal(t, test.username, profile.Username, test.description)
 		assert.Equal(t, "admin@testination.com", profile.Email, test.description)
 		assert.Equal(t, 100, profile.Levels[0].Score, test.description)
-		assert.Equal(t, 0, profile.Levels[0].TimeFreezePointsUsed, test.description)
+		assert.Nil(t, profile.Levels[0].TimeFreezePointsUsed, "A hint that was not bought")
 	}
 	/*
 		type ProfileData = {
@@ -0,0 +1,162 @@
+package api
+
+import (
+	"backend/constants"
+	"backend/database"
+	"backend/database/entity"
+	"backend/database/functionality"
+	"backend/jwt"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type sessionDTO struct {
+	entity.Session
+	Current bool `json:"current"`
+}
+
+func setSessionCookies(c *fiber.Ctx, accessToken string, refreshToken string, expiresAt time.Time) {
+	c.Cookie(&fiber.Cookie{
+		Name:     constants.AUTH_COOKIE_NAME,
+		Value:    accessToken,
+		MaxAge:   int(jwt.ACCESS_TOKEN_DURATION.Seconds()),
+		SameSite: "None",
+		Secure:   true,
+		HTTPOnly: true,
+	})
+	c.Cookie(&fiber.Cookie{
+		Name:     constants.REFRESH_COOKIE_NAME,
+		Value:    refreshToken,
+		Path:     constants.REFRESH_COOKIE_PATH,
+		Expires:  expiresAt,
+		SameSite: "None",
+		Secure:   true,
+		HTTPOnly: true,
+	})
+}
+
+func clearSessionCookies(c *fiber.Ctx) {
+	c.ClearCookie(constants.AUTH_COOKIE_NAME, constants.REFRESH_COOKIE_NAME)
+
+	// Explicitly set cookies with the same properties but with expiration in the past
+	c.Cookie(&fiber.Cookie{
+		Name:     constants.AUTH_COOKIE_NAME,
+		Value:    "",
+		MaxAge:   -1,
+		SameSite: "None",
+		Secure:   true,
+		HTTPOnly: true,
+		Path:     "/", // Match the cookie path to ensure deletion
+	})
+	c.Cookie(&fiber.Cookie{
+		Name:     constants.REFRESH_COOKIE_NAME,
+		Value:    "",
+		MaxAge:   -1,
+		SameSite: "None",
+		Secure:   true,
+		HTTPOnly: true,
+		Path:     constants.REFRESH_COOKIE_PATH,
+	})
+}
+
+// startSession opens a new session for a player that just logged in and sets its cookies
+func startSession(c *fiber.Ctx, db *database.FinalTestinationDB, player *entity.Player) error {
+	session, refreshToken, err := functionality.SessionCreate(db, player.ID, c.Get(fiber.HeaderUserAgent), c.IP())
+	if err != nil {
+		return err
+	}
+
+	accessToken, err := jwt.GenerateJWT(player.ID, player.Role, session.ID)
+	if err != nil {
+		return err
+	}
+
+	setSessionCookies(c, accessToken, refreshToken, session.ExpiresAt)
+	return nil
+}
+
+// refreshSession rotates the refresh token and issues a new access token for the same session
+func refreshSession(c *fiber.Ctx) error {
+	db := c.Locals("db").(*database.FinalTestinationDB)
+
+	refreshToken := c.Cookies(constants.REFRESH_COOKIE_NAME)
+	if refreshToken == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Missing cookie"})
+	}
+
+	session, newRefreshToken, err := functionality.SessionRefresh(db, refreshToken)
+	if errors.Is(err, functionality.ErrSessionInvalid) || errors.Is(err, functionality.ErrSessionReused) {
+		clearSessionCookies(c)
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Couldn't refresh the session"})
+	}
+
+	// The role is read again, so that a refresh picks up role changes
+	player, err := functionality.PlayerGetByID(db, session.PlayerID)
+	if err != nil {
+		clearSessionCookies(c)
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token"})
+	}
+
+	accessToken, err := jwt.GenerateJWT(player.ID, player.Role, session.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "could not generate token"})
+	}
+
+	setSessionCookies(c, accessToken, newRefreshToken, session.ExpiresAt)
+	return c.SendStatus(fiber.StatusOK)
+}
+
+func getSessions(c *fiber.Ctx) error {
+	db := c.Locals("db").(*database.FinalTestinationDB)
+	claims := c.Locals("claims").(*jwt.FinalTestinationClaims)
+
+	sessions, err := functionality.SessionGetActive(db, claims.PlayerID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Couldn't get the sessions"})
+	}
+
+	result := []sessionDTO{}
+	for _, session := range sessions {
+		result = append(result, sessionDTO{Session: session, Current: session.ID == claims.SessionID})
+	}
+
+	return c.JSON(result)
+}
+
+func revokeSession(c *fiber.Ctx) error {
+	db := c.Locals("db").(*database.FinalTestinationDB)
+	claims := c.Locals("claims").(*jwt.FinalTestinationClaims)
+	sessionID := c.Params("sessionId")
+
+	err := functionality.SessionRevoke(db, claims.PlayerID, sessionID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Couldn't find the session you're looking for"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Couldn't revoke the session"})
+	}
+
+	if sessionID == claims.SessionID {
+		clearSessionCookies(c)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// revokeAllSessions logs the player out everywhere, including the current session
+func revokeAllSessions(c *fiber.Ctx) error {
+	db := c.Locals("db").(*database.FinalTestinationDB)
+	claims := c.Locals("claims").(*jwt.FinalTestinationClaims)
+
+	if err := functionality.SessionRevokeAll(db, claims.PlayerID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Couldn't revoke the sessions"})
+	}
+
+	clearSessionCookies(c)
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"backend/constants"
+	"backend/database"
+	"backend/env"
+	"backend/utils"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+type sessionResponse struct {
+	ID      string `json:"ID"`
+	Current bool   `json:"current"`
+}
+
+type sessionCookies struct {
+	access  string
+	refresh string
+}
+
+func readSessionCookies(resp *http.Response) sessionCookies {
+	var cookies sessionCookies
+	for _, cookie := range resp.Cookies() {
+		switch cookie.Name {
+		case constants.AUTH_COOKIE_NAME:
+			cookies.access = cookie.Value
+		case constants.REFRESH_COOKIE_NAME:
+			cookies.refresh = cookie.Value
+		}
+	}
+	return cookies
+}
+
+func sessionRequest(t *testing.T, app *fiber.App, method string, route string, cookies sessionCookies) *http.Response {
+	req := httptest.NewRequest(method, route, nil)
+	req.AddCookie(&http.Cookie{Name: constants.AUTH_COOKIE_NAME, Value: cookies.access})
+	req.AddCookie(&http.Cookie{Name: constants.REFRESH_COOKIE_NAME, Value: cookies.refresh})
+
+	resp, err := app.Test(req, -1) // -1 means no timeout
+	assert.NoError(t, err)
+	return resp
+}
+
+func setUpSessionApp() *fiber.App {
+	db := database.CreateFinalTestinationDB(
+		env.DB_USERNAME, env.DB_PASSWORD,
+		env.DB_HOST, env.DB_PORT, env.DB_NAME)
+	db.CreateSchemas()
+
+	app := fiber.New()
+	playerGroup := app.Group("/player")
+	SetUpPlayerRoutes(&playerGroup, db)
+	return app
+}
+
+func TestSessionRefresh(t *testing.T) {
+	app := setUpSessionApp()
+
+	login := readSessionCookies(utils.MockLogin(t, app, "test", "rootroot"))
+	assert.NotEmpty(t, login.access)
+	assert.NotEmpty(t, login.refresh)
+
+	resp := sessionRequest(t, app, "GET", "/player/sessions", login)
+	assert.Equal(t, 200, resp.StatusCode, "List the sessions")
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	var sessions []sessionResponse
+	assert.NoError(t, json.Unmarshal(body, &sessions))
+	current := utils.Filter(sessions, func(s sessionResponse) bool { return s.Current })
+	assert.Equal(t, 1, len(current), "Exactly one session is the current one")
+
+	resp = sessionRequest(t, app, "POST", "/player/refresh", login)
+	assert.Equal(t, 200, resp.StatusCode, "Refresh the session")
+	refreshed := readSessionCookies(resp)
+	assert.NotEmpty(t, refreshed.access)
+	assert.NotEqual(t, login.refresh, refreshed.refresh, "The refresh token is rotated")
+
+	resp = sessionRequest(t, app, "GET", "/player/profile", refreshed)
+	assert.Equal(t, 200, resp.StatusCode, "The new access token is valid")
+
+	resp = sessionRequest(t, app, "POST", "/player/refresh", login)
+	assert.Equal(t, 401, resp.StatusCode, "Reusing a refresh token fails")
+
+	resp = sessionRequest(t, app, "GET", "/player/profile", refreshed)
+	assert.Equal(t, 401, resp.StatusCode, "Reusing a refresh token revokes the session")
+
+	resp = sessionRequest(t, app, "POST", "/player/refresh", refreshed)
+	assert.Equal(t, 401, resp.StatusCode, "The last refresh token of a revoked session is rejected")
+}
+
+func TestSessionRevocation(t *testing.T) {
+	app := setUpSessionApp()
+
+	first := readSessionCookies(utils.MockLogin(t, app, "test", "rootroot"))
+	second := readSessionCookies(utils.MockLogin(t, app, "test", "rootroot"))
+
+	resp := sessionRequest(t, app, "GET", "/player/sessions", second)
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	var sessions []sessionResponse
+	assert.NoError(t, json.Unmarshal(body, &sessions))
+	var secondID string
+	for _, session := range sessions {
+		if session.Current {
+			secondID = session.ID
+		}
+	}
+	assert.NotEmpty(t, secondID)
+
+	resp = sessionRequest(t, app, "DELETE", "/player/sessions/"+secondID, first)
+	assert.Equal(t, 204, resp.StatusCode, "Revoke another session")
+
+	resp = sessionRequest(t, app, "DELETE", "/player/sessions/"+secondID, first)
+	assert.Equal(t, 404, resp.StatusCode, "Revoke a session that is already revoked")
+
+	resp = sessionRequest(t, app, "GET", "/player/profile", second)
+	assert.Equal(t, 401, resp.StatusCode, "The access token of a revoked session is rejected")
+
+	resp = sessionRequest(t, app, "GET", "/player/profile", first)
+	assert.Equal(t, 200, resp.StatusCode, "The other sessions are still valid")
+
+	resp = sessionRequest(t, app, "DELETE", "/player/sessions", first)
+	assert.Equal(t, 204, resp.StatusCode, "Revoke every session")
+
+	resp = sessionRequest(t, app, "GET", "/player/profile", first)
+	assert.Equal(t, 401, resp.StatusCode, "The current session is revoked too")
+
+	third := readSessionCookies(utils.MockLogin(t, app, "test", "rootroot"))
+	resp = sessionRequest(t, app, "POST", "/player/logout", third)
+	assert.Equal(t, 200, resp.StatusCode, "Log out")
+
+	resp = sessionRequest(t, app, "POST", "/player/refresh", third)
+	assert.Equal(t, 401, resp.StatusCode, "Logging out revokes the session")
+}
@@ -0,0 +1,46 @@
+package checkers
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	CHECKER_EXACT      = "exact"
+	CHECKER_UNORDERED  = "unordered"
+	CHECKER_REGEX      = "regex"
+	CHECKER_NORMALIZED = "normalized"
+)
+
+var CHECKERS = []string{CHECKER_EXACT, CHECKER_UNORDERED, CHECKER_REGEX, CHECKER_NORMALIZED}
+
+var ErrInvalidChecker = errors.New("invalid checker")
+
+// Checker tells whether an answer is correct and which of its blocks are in the right place
+type Checker interface {
+	Check(answer []string) (matches []bool, correct bool)
+}
+
+// New creates the checker of a game. `solutions` are the accepted answers, starting from the one defined
+// by the order of the blocks, while `pattern` is only used by the regex checker.
+// An empty kind selects the exact checker, which is what every game used before checkers existed.
+func New(kind string, solutions [][]string, pattern string) (Checker, error) {
+	switch kind {
+	case "", CHECKER_EXACT:
+		return &ExactChecker{Solutions: solutions}, nil
+	case CHECKER_UNORDERED:
+		return &UnorderedChecker{Solutions: solutions}, nil
+	case CHECKER_REGEX:
+		return NewRegexChecker(pattern, solutions)
+	case CHECKER_NORMALIZED:
+		return &NormalizedChecker{Solutions: solutions}, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown checker %q", ErrInvalidChecker, kind)
+	}
+}
+
+// Validate checks the configuration of a checker without building it
+func Validate(kind string, pattern string) error {
+	_, err := New(kind, nil, pattern)
+	return err
+}
@@ -0,0 +1,231 @@
+package checkers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Unit testing for sunction TestMatchArrays
+func TestMatchArrays(t *testing.T) {
+	tests := []struct {
+		description     string // description of the test case
+		answer          []string
+		solution        []string
+		expectedMatches []bool
+		expectedResult  bool
+	}{
+		{
+			description: "Non-empty arrays correctly match",
+			answer: []string{
+				"a",
+				"b",
+				"c",
+			},
+			solution: []string{
+				"a",
+				"b",
+				"c",
+			},
+			expectedMatches: []bool{
+				true,
+				true,
+				true,
+			},
+			expectedResult: true,
+		},
+		{
+			description:     "Empty arrays correctly match",
+			answer:          []string{},
+			solution:        []string{},
+			expectedMatches: []bool{},
+			expectedResult:  true,
+		},
+		{
+			description: "Arrays of different lengths do not match",
+			answer: []string{
+				"a",
+				"b",
+			},
+			solution: []string{
+				"a",
+				"b",
+				"c",
+			},
+			expectedMatches: []bool{
+				false,
+				false,
+				false,
+			},
+			expectedResult: false,
+		},
+		{
+			description: "Arrays of same length but different elements do not match",
+			answer: []string{
+				"a",
+				"b",
+				"1",
+			},
+			solution: []string{
+				"a",
+				"b",
+				"c",
+			},
+			expectedMatches: []bool{
+				true,
+				true,
+				false,
+			},
+			expectedResult: false,
+		},
+	}
+
+	for _, test := range tests {
+		matches, result := ArraysMatch(test.solution, test.answer)
+
+		assert.Equalf(t, test.expectedMatches, matches, test.description)
+		assert.Equalf(t, test.expectedResult, result, test.description)
+	}
+}
+
+func TestSolutionsMatch(t *testing.T) {
+	solutions := [][]string{
+		{"<iframe", "width=0", "height=0", "/>"},
+		{"<iframe", "height=0", "width=0", "/>"},
+	}
+
+	tests := []struct {
+		description     string
+		answer          []string
+		expectedMatches []bool
+		expectedResult  bool
+	}{
+		{
+			description:     "The main solution is accepted",
+			answer:          []string{"<iframe", "width=0", "height=0", "/>"},
+			expectedMatches: []bool{true, true, true, true},
+			expectedResult:  true,
+		},
+		{
+			description:     "An alternative solution is accepted",
+			answer:          []string{"<iframe", "height=0", "width=0", "/>"},
+			expectedMatches: []bool{true, true, true, true},
+			expectedResult:  true,
+		},
+		{
+			description:     "The matches are those of the closest solution",
+			answer:          []string{"<iframe", "height=0", "width=0", "<img"},
+			expectedMatches: []bool{true, true, true, false},
+			expectedResult:  false,
+		},
+		{
+			description:     "The first solution wins when the answer is as close to every solution",
+			answer:          []string{"<iframe", "width=0", "width=0", "<img"},
+			expectedMatches: []bool{true, true, false, false},
+			expectedResult:  false,
+		},
+	}
+
+	for _, test := range tests {
+		matches, result := SolutionsMatch(solutions, test.answer)
+
+		assert.Equalf(t, test.expectedMatches, matches, test.description)
+		assert.Equalf(t, test.expectedResult, result, test.description)
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		description string
+		kind        string
+		pattern     string
+		valid       bool
+	}{
+		{description: "A game without checker uses the exact one", kind: "", valid: true},
+		{description: "The unordered checker", kind: CHECKER_UNORDERED, valid: true},
+		{description: "The regex checker with a pattern", kind: CHECKER_REGEX, pattern: `'\s*OR\s+1=1`, valid: true},
+		{description: "The regex checker without a pattern", kind: CHECKER_REGEX, valid: false},
+		{description: "The regex checker with an invalid pattern", kind: CHECKER_REGEX, pattern: `(`, valid: false},
+		{description: "An unknown checker", kind: "fuzzy", valid: false},
+	}
+
+	for _, test := range tests {
+		err := Validate(test.kind, test.pattern)
+		if test.valid {
+			assert.NoError(t, err, test.description)
+		} else {
+			assert.ErrorIs(t, err, ErrInvalidChecker, test.description)
+		}
+	}
+}
+
+func TestCheckers(t *testing.T) {
+	solutions := [][]string{{"' OR ", "1=1", " --"}}
+	regex, err := NewRegexChecker(`'\s*OR\s+(1=1|'a'='a')\s*--`, solutions)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		description     string
+		checker         Checker
+		answer          []string
+		expectedMatches []bool
+		expectedResult  bool
+	}{
+		{
+			description:     "The unordered checker accepts the blocks in any order",
+			checker:         &UnorderedChecker{Solutions: solutions},
+			answer:          []string{" --", "' OR ", "1=1"},
+			expectedMatches: []bool{true, true, true},
+			expectedResult:  true,
+		},
+		{
+			description:     "The unordered checker marks the blocks that are not in the solution",
+			checker:         &UnorderedChecker{Solutions: solutions},
+			answer:          []string{" --", "' OR ", "1=2"},
+			expectedMatches: []bool{true, true, false},
+			expectedResult:  false,
+		},
+		{
+			description:     "The unordered checker does not count a block twice",
+			checker:         &UnorderedChecker{Solutions: solutions},
+			answer:          []string{"1=1", "1=1", " --"},
+			expectedMatches: []bool{true, false, true},
+			expectedResult:  false,
+		},
+		{
+			description:     "The regex checker accepts an answer that matches the pattern",
+			checker:         regex,
+			answer:          []string{"'OR ", "'a'='a'", "--"},
+			expectedMatches: []bool{true, true, true},
+			expectedResult:  true,
+		},
+		{
+			description:     "The regex checker must match the whole answer",
+			checker:         regex,
+			answer:          []string{"' OR ", "1=1", " --", "DROP"},
+			expectedMatches: []bool{false, false, false},
+			expectedResult:  false,
+		},
+		{
+			description:     "The normalized checker ignores case, whitespace and quotes",
+			checker:         &NormalizedChecker{Solutions: solutions},
+			answer:          []string{"\"or", "1 = 1", "--"},
+			expectedMatches: []bool{true, true, true},
+			expectedResult:  true,
+		},
+		{
+			description:     "The normalized checker compares the content",
+			checker:         &NormalizedChecker{Solutions: solutions},
+			answer:          []string{"' OR ", "1=2", " --"},
+			expectedMatches: []bool{true, false, true},
+			expectedResult:  false,
+		},
+	}
+
+	for _, test := range tests {
+		matches, result := test.checker.Check(test.answer)
+
+		assert.Equalf(t, test.expectedMatches, matches, test.description)
+		assert.Equalf(t, test.expectedResult, result, test.description)
+	}
+}
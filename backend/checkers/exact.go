@@ -0,0 +1,58 @@
+package checkers
+
+// ExactChecker accepts the answers that have the blocks of a solution in the same order
+type ExactChecker struct {
+	Solutions [][]string
+}
+
+func (c *ExactChecker) Check(answer []string) ([]bool, bool) {
+	return SolutionsMatch(c.Solutions, answer)
+}
+
+func ArraysMatch(solution []string, answer []string) ([]bool, bool) {
+	correct_indexes := make([]bool, len(solution))
+
+	if len(solution) != len(answer) {
+		return correct_indexes, false
+	}
+
+	is_all_correct := true
+
+	for i, v := range answer {
+		correct_indexes[i] = solution[i] == v
+		if !correct_indexes[i] {
+			is_all_correct = false
+		}
+	}
+
+	return correct_indexes, is_all_correct
+}
+
+// SolutionsMatch compares the answer with every accepted solution. The matches are those of the
+// solution the answer is closest to, so that the player is not told to move blocks that fit another solution.
+func SolutionsMatch(solutions [][]string, answer []string) ([]bool, bool) {
+	var closest []bool
+	closestCount := -1
+
+	for _, solution := range solutions {
+		correct_indexes, is_all_correct := ArraysMatch(solution, answer)
+		if is_all_correct {
+			return correct_indexes, true
+		}
+
+		count := 0
+		for _, correct := range correct_indexes {
+			if correct {
+				count++
+			}
+		}
+		if count > closestCount {
+			closest, closestCount = correct_indexes, count
+		}
+	}
+
+	if closest == nil {
+		closest = []bool{}
+	}
+	return closest, false
+}
@@ -0,0 +1,39 @@
+package checkers
+
+import (
+	"strings"
+	"unicode"
+)
+
+// NormalizedChecker accepts the answers whose blocks, joined together, are equal to a solution
+// ignoring case, whitespace and the kind of quotes, so that `' OR 1=1` and `"or 1 = 1` are the same payload.
+type NormalizedChecker struct {
+	Solutions [][]string
+}
+
+func normalize(blocks []string) string {
+	var builder strings.Builder
+	for _, r := range strings.Join(blocks, "") {
+		switch {
+		case unicode.IsSpace(r):
+			continue
+		case r == '"' || r == '`':
+			builder.WriteRune('\'')
+		default:
+			builder.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return builder.String()
+}
+
+func (c *NormalizedChecker) Check(answer []string) ([]bool, bool) {
+	normalized := normalize(answer)
+	for _, solution := range c.Solutions {
+		if normalize(solution) == normalized {
+			return allMatch(len(answer)), true
+		}
+	}
+
+	matches, _ := SolutionsMatch(c.Solutions, answer)
+	return matches, false
+}
@@ -0,0 +1,43 @@
+package checkers
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// RegexChecker accepts the answers whose blocks, joined together, match the whole pattern.
+// Since a pattern cannot tell which block is wrong, the matches of a wrong answer come from the solutions.
+type RegexChecker struct {
+	Pattern   *regexp.Regexp
+	Solutions [][]string
+}
+
+func NewRegexChecker(pattern string, solutions [][]string) (*RegexChecker, error) {
+	if pattern == "" {
+		return nil, fmt.Errorf("%w: the regex checker needs a pattern", ErrInvalidChecker)
+	}
+	compiled, err := regexp.Compile(`^(?:` + pattern + `)$`)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidChecker, err)
+	}
+
+	return &RegexChecker{Pattern: compiled, Solutions: solutions}, nil
+}
+
+func (c *RegexChecker) Check(answer []string) ([]bool, bool) {
+	if c.Pattern.MatchString(strings.Join(answer, "")) {
+		return allMatch(len(answer)), true
+	}
+
+	matches, _ := SolutionsMatch(c.Solutions, answer)
+	return matches, false
+}
+
+func allMatch(length int) []bool {
+	matches := make([]bool, length)
+	for i := range matches {
+		matches[i] = true
+	}
+	return matches
+}
@@ -0,0 +1,38 @@
+package checkers
+
+// UnorderedChecker accepts the answers that use the blocks of a solution in any order.
+// A block is marked as matching when the closest solution contains it.
+type UnorderedChecker struct {
+	Solutions [][]string
+}
+
+func (c *UnorderedChecker) Check(answer []string) ([]bool, bool) {
+	closest := []bool{}
+	closestCount := -1
+
+	for _, solution := range c.Solutions {
+		remaining := map[string]int{}
+		for _, block := range solution {
+			remaining[block]++
+		}
+
+		matches := make([]bool, len(answer))
+		count := 0
+		for i, block := range answer {
+			if remaining[block] > 0 {
+				remaining[block]--
+				matches[i] = true
+				count++
+			}
+		}
+
+		if len(answer) == len(solution) && count == len(solution) {
+			return matches, true
+		}
+		if count > closestCount {
+			closest, closestCount = matches, count
+		}
+	}
+
+	return closest, false
+}
@@ -0,0 +1,37 @@
+package commands
+
+import (
+	"backend/database"
+	"fmt"
+)
+
+const usage = `usage: backend [command]
+
+Without a command the API server is started. Available commands:
+  migrate up
+  migrate down [-steps n]
+  migrate status
+  levels export [-format yaml|json] [-out dir] [gameId...]
+  levels import <file or directory>...
+  leaderboard rebuild`
+
+// Run executes the command line subcommand described by `args`.
+// Every command other than `migrate` requires the schema to be up to date.
+func Run(db *database.FinalTestinationDB, args []string) error {
+	if args[0] == "migrate" {
+		return runMigrate(db, args[1:])
+	}
+
+	if err := db.CheckSchemaUpToDate(); err != nil {
+		return fmt.Errorf("%w, run `migrate up` first", err)
+	}
+
+	switch args[0] {
+	case "levels":
+		return runLevels(db, args[1:])
+	case "leaderboard":
+		return runLeaderboard(db, args[1:])
+	default:
+		return fmt.Errorf("unknown command %q\n%s", args[0], usage)
+	}
+}
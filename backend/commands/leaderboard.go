@@ -0,0 +1,32 @@
+package commands
+
+import (
+	"backend/database"
+	"backend/database/functionality"
+	"backend/loggers"
+	"errors"
+	"fmt"
+)
+
+func runLeaderboard(db *database.FinalTestinationDB, args []string) error {
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+
+	switch args[0] {
+	case "rebuild":
+		return rebuildStandings(db)
+	default:
+		return fmt.Errorf("unknown leaderboard command %q\n%s", args[0], usage)
+	}
+}
+
+// rebuildStandings recomputes the standings of the all-time leaderboard from the coin ledger
+func rebuildStandings(db *database.FinalTestinationDB) error {
+	players, err := functionality.StandingsRebuild(db)
+	if err != nil {
+		return err
+	}
+	loggers.Info.Printf("Rebuilt the standings of %d players", players)
+	return nil
+}
@@ -0,0 +1,160 @@
+package commands
+
+import (
+	"backend/database"
+	"backend/database/functionality"
+	"backend/levels"
+	"backend/loggers"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+func runLevels(db *database.FinalTestinationDB, args []string) error {
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+
+	switch args[0] {
+	case "export":
+		return exportLevels(db, args[1:])
+	case "import":
+		return importLevels(db, args[1:])
+	default:
+		return fmt.Errorf("unknown levels command %q\n%s", args[0], usage)
+	}
+}
+
+// exportLevels writes one file per game in the output directory, named after the ID of the game.
+// When no game is specified, every game is exported.
+func exportLevels(db *database.FinalTestinationDB, args []string) error {
+	flags := flag.NewFlagSet("levels export", flag.ContinueOnError)
+	format := flags.String("format", levels.FORMAT_YAML, "format of the exported files (yaml or json)")
+	out := flags.String("out", ".", "directory where the files are written")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var gameIDs []uuid.UUID
+	if flags.NArg() == 0 {
+		games, err := functionality.GameGetAll(db)
+		if err != nil {
+			return err
+		}
+		for _, game := range games {
+			gameIDs = append(gameIDs, uuid.MustParse(game.ID))
+		}
+	} else {
+		for _, arg := range flags.Args() {
+			id, err := uuid.Parse(arg)
+			if err != nil {
+				return fmt.Errorf("%q is not a valid game ID", arg)
+			}
+			gameIDs = append(gameIDs, id)
+		}
+	}
+
+	if err := os.MkdirAll(*out, 0755); err != nil {
+		return err
+	}
+
+	for _, id := range gameIDs {
+		game, err := functionality.GameGetById(db, id)
+		if err != nil {
+			return fmt.Errorf("couldn't get game %s: %w", id, err)
+		}
+
+		pkg := levels.FromGame(game)
+		data, err := levels.Encode(&pkg, *format)
+		if err != nil {
+			return err
+		}
+
+		path := filepath.Join(*out, fmt.Sprintf("%s.%s", game.ID, *format))
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return err
+		}
+		loggers.Info.Printf("Exported %q to %s", game.Title, path)
+	}
+
+	return nil
+}
+
+// importLevels imports the given files, or every level file contained in the given directories.
+// The format of each file is chosen from its extension.
+func importLevels(db *database.FinalTestinationDB, args []string) error {
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+
+	var paths []string
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			paths = append(paths, arg)
+			continue
+		}
+
+		entries, err := os.ReadDir(arg)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() && formatOf(entry.Name()) != "" {
+				paths = append(paths, filepath.Join(arg, entry.Name()))
+			}
+		}
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		format := formatOf(path)
+		if format == "" {
+			return fmt.Errorf("%s: unknown file extension", path)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		pkg, err := levels.Decode(data, format)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+
+		game := pkg.Game()
+		created, err := functionality.GameImport(db, &game)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+
+		if created {
+			loggers.Info.Printf("Created %q from %s", game.Title, path)
+		} else {
+			loggers.Info.Printf("Updated %q from %s", game.Title, path)
+		}
+	}
+
+	return nil
+}
+
+func formatOf(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return levels.FORMAT_YAML
+	case ".json":
+		return levels.FORMAT_JSON
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,54 @@
+package commands
+
+import (
+	"backend/database"
+	"backend/loggers"
+	"errors"
+	"flag"
+	"fmt"
+)
+
+func runMigrate(db *database.FinalTestinationDB, args []string) error {
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := db.MigrateUp()
+		for _, migration := range applied {
+			loggers.Info.Printf("Applied migration %d_%s", migration.Version, migration.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			loggers.Info.Println("The database schema is already up to date")
+		}
+		return err
+	case "down":
+		flags := flag.NewFlagSet("migrate down", flag.ContinueOnError)
+		steps := flags.Int("steps", 1, "number of migrations to revert")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		reverted, err := db.MigrateDown(*steps)
+		for _, migration := range reverted {
+			loggers.Info.Printf("Reverted migration %d_%s", migration.Version, migration.Name)
+		}
+		return err
+	case "status":
+		status, err := db.MigrationStatus()
+		if err != nil {
+			return err
+		}
+		for _, s := range status {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = "applied at " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%s\t%s\n", s.Version, s.Name, applied)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate command %q\n%s", args[0], usage)
+	}
+}
@@ -1,4 +1,80 @@
 package constants
 
 const AUTH_COOKIE_NAME = "testination-login"
+const REFRESH_COOKIE_NAME = "testination-refresh"
+
+// The refresh cookie is only sent to the player routes, which include refresh and logout
+const REFRESH_COOKIE_PATH = "/player"
 const PAGE_SIZE = 25
+
+// Roles a player can have, from the least to the most privileged
+const (
+	ROLE_PLAYER    = "player"
+	ROLE_AUTHOR    = "author"
+	ROLE_MODERATOR = "moderator"
+	ROLE_ADMIN     = "admin"
+)
+
+var ROLES = []string{ROLE_PLAYER, ROLE_AUTHOR, ROLE_MODERATOR, ROLE_ADMIN}
+
+// Purposes of the single-use tokens sent by email
+const (
+	TOKEN_PURPOSE_VERIFY_EMAIL   = "verify-email"
+	TOKEN_PURPOSE_RESET_PASSWORD = "reset-password"
+)
+
+// Events of the clock of a level
+const (
+	CLOCK_EVENT_START  = "start"
+	CLOCK_EVENT_PAUSE  = "pause"
+	CLOCK_EVENT_RESUME = "resume"
+	CLOCK_EVENT_FREEZE = "freeze"
+	CLOCK_EVENT_END    = "end"
+)
+
+// Kinds of the transactions of the coin ledger
+const (
+	COIN_EARN   = "earn"
+	COIN_SPEND  = "spend"
+	COIN_GRANT  = "grant"
+	COIN_REFUND = "refund"
+)
+
+// Reasons of the coins earned by completing a level, the hints are spent as "hint_" followed by their type
+const (
+	COIN_REASON_LEVEL_COMPLETED = "level_completed"
+	COIN_REASON_HINT_PREFIX     = "hint_"
+)
+
+// Types of the hints a player can buy
+const (
+	HINT_TEXTUAL = "textual"
+	HINT_FILL    = "fill"
+	HINT_FREEZE  = "freeze"
+	// Removes some of the decoy blocks, the ones that are not part of the solution
+	HINT_ELIMINATE = "eliminate"
+)
+
+// Time windows of the leaderboards, the week starts on Monday
+const (
+	LEADERBOARD_WINDOW_ALL   = "all"
+	LEADERBOARD_WINDOW_MONTH = "month"
+	LEADERBOARD_WINDOW_WEEK  = "week"
+)
+
+// How the entries of a leaderboard with the same score are ranked
+const (
+	RANKING_COMPETITION = "competition"
+	RANKING_DENSE       = "dense"
+)
+
+// The largest page of a leaderboard that can be requested
+const MAX_PAGE_SIZE = 100
+
+// Types of the events streamed live by `/events`
+const (
+	LIVE_EVENT_LEVEL_COMPLETED = "level_completed"
+	// The first player to complete a level
+	LIVE_EVENT_FIRST_SOLVE  = "first_solve"
+	LIVE_EVENT_RANK_CHANGED = "rank_changed"
+)
@@ -1,7 +1,6 @@
 package database
 
 import (
-	"backend/database/entity"
 	"backend/loggers"
 	"fmt"
 	"log"
@@ -39,14 +38,13 @@ type FinalTestinationDB struct {
 	Orm *gorm.DB
 }
 
+// CreateSchemas applies every pending migration, see the `migrations` package
 func (db *FinalTestinationDB) CreateSchemas() {
-	if err := db.Orm.AutoMigrate(
-		&entity.Block{},
-		&entity.Game{},
-		&entity.Player{},
-		&entity.PlayerGame{},
-		&entity.Icon{},
-	); err != nil {
+	applied, err := db.MigrateUp()
+	if err != nil {
 		loggers.Error.Fatalf("Error creating schemas: %s", err)
 	}
+	for _, migration := range applied {
+		loggers.Info.Printf("Applied migration %d_%s", migration.Version, migration.Name)
+	}
 }
@@ -0,0 +1,18 @@
+package entity
+
+import (
+	"backend/utils"
+	"time"
+)
+
+// Attempt is an answer submitted by a player, `Matches` tells which blocks were in the right position
+type Attempt struct {
+	utils.Model
+	PlayerID       string    `gorm:"not null" json:"-"`
+	GameID         string    `gorm:"not null" json:"game_id"`
+	Blocks         []string  `gorm:"not null;type:jsonb;serializer:json" json:"blocks"`
+	Matches        []bool    `gorm:"not null;type:jsonb;serializer:json" json:"matches"`
+	Correct        bool      `gorm:"not null" json:"correct"`
+	SubmittedAt    time.Time `gorm:"not null" json:"submitted_at"`
+	ElapsedSeconds *int64    `json:"elapsed_seconds"`
+}
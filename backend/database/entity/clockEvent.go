@@ -0,0 +1,16 @@
+package entity
+
+import (
+	"backend/utils"
+	"time"
+)
+
+// ClockEvent changes the state of the clock of a level, `Until` is only set for freezes
+type ClockEvent struct {
+	utils.Model
+	PlayerID string     `gorm:"not null" json:"-"`
+	GameID   string     `gorm:"not null" json:"-"`
+	Kind     string     `gorm:"not null" json:"kind"`
+	At       time.Time  `gorm:"not null" json:"at"`
+	Until    *time.Time `json:"until,omitempty"`
+}
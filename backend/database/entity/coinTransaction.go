@@ -0,0 +1,18 @@
+package entity
+
+import (
+	"backend/utils"
+	"time"
+)
+
+// CoinTransaction is an entry of the coin ledger of a player, `Amount` is negative when coins are spent
+type CoinTransaction struct {
+	utils.Model
+	PlayerID  string    `gorm:"not null" json:"-"`
+	Kind      string    `gorm:"not null" json:"kind"`
+	Amount    int       `gorm:"not null" json:"amount"`
+	Reason    string    `gorm:"not null" json:"reason"`
+	GameID    *string   `json:"game_id"`
+	Reference *string   `json:"reference"`
+	CreatedAt time.Time `gorm:"not null" json:"created_at"`
+}
@@ -1,6 +1,7 @@
 package entity
 
 import (
+	"backend/scoring"
 	"backend/utils"
 )
 
@@ -21,9 +22,24 @@ type Game struct {
 	GreatTimeslot      int          `gorm:"not null" json:"great_timeslot"`       // In seconds
 	MediumTimeslot     int          `gorm:"not null" json:"medium_timeslot"`      // In seconds
 	NotSoGoodTimeslot  int          `gorm:"not null" json:"not_so_good_timeslot"` // In seconds
-	TextualHintPrice   int          `gorm:"not null" json:"textual_hint_price"`
-	TextualHint        string       `gorm:"not null" json:"textual_hint"`
 	HintSolutionPrice  int          `gorm:"not null" json:"hint_solution_price"`
 	TimeFreezePrice    int          `gorm:"not null" json:"time_freeze_price"`
 	TimeFreezeDuration int          `gorm:"not null" json:"time_freeze_duration"`
+	// How many decoy blocks are removed by each eliminate hint, 0 if the level does not offer it
+	EliminateDecoysCount int `gorm:"not null" json:"eliminate_decoys_count"`
+	EliminateDecoysPrice int `gorm:"not null" json:"eliminate_decoys_price"`
+	// Revealed one at a time, in order
+	TextualHints []TextualHint `gorm:"not null;type:jsonb;serializer:json" json:"textual_hints"`
+	// Other accepted answers, each one with a block content for every position of the solution
+	AlternativeSolutions [][]string `gorm:"not null;type:jsonb;serializer:json" json:"alternative_solutions"`
+	// How the answers are checked, one of `checkers.CHECKERS`
+	Checker        string `gorm:"not null" json:"checker"`
+	CheckerPattern string `gorm:"not null" json:"checker_pattern"`
+	// How the score is computed when the level is completed
+	Scoring scoring.Policy `gorm:"not null;type:jsonb;serializer:json" json:"scoring"`
+}
+
+type TextualHint struct {
+	Content string `json:"content"`
+	Price   int    `json:"price"`
 }
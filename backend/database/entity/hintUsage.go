@@ -0,0 +1,20 @@
+package entity
+
+import (
+	"backend/utils"
+	"time"
+)
+
+// HintUsage is a hint bought by a player, `Replay` is set for the hints used after completing the level
+type HintUsage struct {
+	utils.Model
+	PlayerID   string `gorm:"not null" json:"-"`
+	GameID     string `gorm:"not null" json:"-"`
+	HintType   string `gorm:"not null" json:"hint_type"`
+	Price      int    `gorm:"not null" json:"price"`
+	BlockOrder *int   `json:"block_order"`
+	// The position of a textual hint in the list of the game
+	HintIndex *int      `json:"hint_index"`
+	Replay    bool      `gorm:"not null" json:"replay"`
+	UsedAt    time.Time `gorm:"not null" json:"used_at"`
+}
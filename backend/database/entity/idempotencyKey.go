@@ -0,0 +1,13 @@
+package entity
+
+import "time"
+
+// IdempotencyKey remembers the response of a request, so that retrying it does not execute it twice
+type IdempotencyKey struct {
+	PlayerID  string `gorm:"primaryKey"`
+	Key       string `gorm:"primaryKey"`
+	Scope     string `gorm:"not null"`
+	Request   string `gorm:"not null"`
+	Response  *string
+	CreatedAt time.Time `gorm:"not null"`
+}
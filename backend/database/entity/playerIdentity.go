@@ -0,0 +1,16 @@
+package entity
+
+import (
+	"backend/utils"
+	"time"
+)
+
+// PlayerIdentity links an account of an OpenID Connect provider to a player
+type PlayerIdentity struct {
+	utils.Model
+	PlayerID  string    `gorm:"not null" json:"-"`
+	Provider  string    `gorm:"not null" json:"provider"`
+	Subject   string    `gorm:"not null" json:"-"`
+	Email     string    `gorm:"not null" json:"email"`
+	CreatedAt time.Time `gorm:"not null" json:"created_at"`
+}
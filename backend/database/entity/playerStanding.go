@@ -0,0 +1,10 @@
+package entity
+
+import "time"
+
+// PlayerStanding is the all-time score of a player, the balance of its coin ledger, and when it was reached
+type PlayerStanding struct {
+	PlayerID  string    `gorm:"primaryKey"`
+	Score     int       `gorm:"not null"`
+	ReachedAt time.Time `gorm:"not null"`
+}
@@ -0,0 +1,16 @@
+package entity
+
+import (
+	"backend/utils"
+	"time"
+)
+
+// PlayerToken records a token sent by email, its ID is the `jti` claim of the token
+type PlayerToken struct {
+	utils.Model
+	PlayerID  string     `gorm:"not null" json:"-"`
+	Purpose   string     `gorm:"not null" json:"purpose"`
+	CreatedAt time.Time  `gorm:"not null" json:"created_at"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+}
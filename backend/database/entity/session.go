@@ -0,0 +1,20 @@
+package entity
+
+import (
+	"backend/utils"
+	"time"
+)
+
+// Session is opened at every login and lives as long as its refresh token can be rotated
+type Session struct {
+	utils.Model
+	PlayerID          string     `gorm:"not null" json:"-"`
+	RefreshTokenHash  string     `gorm:"not null" json:"-"`
+	PreviousTokenHash *string    `json:"-"`
+	UserAgent         string     `gorm:"not null" json:"user_agent"`
+	IP                string     `gorm:"not null" json:"ip"`
+	CreatedAt         time.Time  `gorm:"not null" json:"created_at"`
+	LastUsedAt        time.Time  `gorm:"not null" json:"last_used_at"`
+	ExpiresAt         time.Time  `gorm:"not null" json:"expires_at"`
+	RevokedAt         *time.Time `json:"-"`
+}
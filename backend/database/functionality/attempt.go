@@ -0,0 +1,142 @@
+package functionality
+
+import (
+	"backend/database"
+	"backend/database/entity"
+	"backend/utils"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type AttemptAnswerCount struct {
+	Blocks []string `gorm:"serializer:json" json:"blocks"`
+	Count  int      `json:"count"`
+}
+
+type AttemptPositionErrors struct {
+	Position  int     `json:"position"`
+	ErrorRate float64 `json:"error_rate"`
+}
+
+// AttemptsSummaryDTO aggregates the attempts of every player on a game, to find what confuses them
+type AttemptsSummaryDTO struct {
+	TotalAttempts   int     `json:"total_attempts"`
+	CorrectAttempts int     `json:"correct_attempts"`
+	Players         int     `json:"players"`
+	PlayersSolved   int     `json:"players_solved"`
+	AverageAttempts float64 `json:"average_attempts_to_solve"`
+	// The most common wrong answers, the most frequent first
+	CommonWrongAnswers []AttemptAnswerCount `json:"common_wrong_answers"`
+	// How often each position of the solution is wrong in the wrong answers
+	PositionErrors []AttemptPositionErrors `json:"position_errors"`
+}
+
+// Number of wrong answers listed in the summary
+const ATTEMPTS_SUMMARY_ANSWERS = 10
+
+// AttemptCreate records a submitted answer with the time on the clock, if the level is being played
+func AttemptCreate(database *database.FinalTestinationDB, gameID uuid.UUID, playerID uuid.UUID, blocks []string, matches []bool, correct bool) (*entity.Attempt, error) {
+	now := time.Now()
+	attempt := entity.Attempt{
+		Model:       utils.Model{ID: uuid.New().String()},
+		PlayerID:    playerID.String(),
+		GameID:      gameID.String(),
+		Blocks:      blocks,
+		Matches:     matches,
+		Correct:     correct,
+		SubmittedAt: now,
+	}
+	if attempt.Blocks == nil {
+		attempt.Blocks = []string{}
+	}
+
+	var playerGame entity.PlayerGame
+	result := database.Orm.Select("end_time").Where("game_id = ? AND player_id = ?", gameID, playerID).First(&playerGame)
+	if result.Error == nil && playerGame.EndTime == nil {
+		clock, err := clockElapsedOf(database.Orm, gameID, playerID, now)
+		if err != nil {
+			return nil, err
+		}
+		elapsed := int64(clock.Seconds())
+		attempt.ElapsedSeconds = &elapsed
+	} else if result.Error != nil && !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, result.Error
+	}
+
+	if err := database.Orm.Create(&attempt).Error; err != nil {
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+func AttemptGetByPlayer(database *database.FinalTestinationDB, gameID uuid.UUID, playerID uuid.UUID) ([]entity.Attempt, error) {
+	attempts := []entity.Attempt{}
+	result := database.Orm.
+		Where("game_id = ? AND player_id = ?", gameID, playerID).
+		Order("submitted_at").
+		Find(&attempts)
+
+	return attempts, result.Error
+}
+
+func AttemptsSummary(database *database.FinalTestinationDB, gameID uuid.UUID) (*AttemptsSummaryDTO, error) {
+	summary := AttemptsSummaryDTO{
+		CommonWrongAnswers: []AttemptAnswerCount{},
+		PositionErrors:     []AttemptPositionErrors{},
+	}
+
+	result := database.Orm.Model(&entity.Attempt{}).
+		Where("game_id = ?", gameID).
+		Select(`COUNT(*) AS total_attempts,
+			COUNT(*) FILTER (WHERE correct) AS correct_attempts,
+			COUNT(DISTINCT player_id) AS players,
+			COUNT(DISTINCT player_id) FILTER (WHERE correct) AS players_solved`).
+		Scan(&summary)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	// Attempts up to and including the first correct one, for the players that solved the level
+	result = database.Orm.Raw(`SELECT COALESCE(AVG(tries), 0) FROM (
+			SELECT a.player_id, COUNT(*) AS tries
+			FROM attempts a
+			JOIN (
+				SELECT player_id, MIN(submitted_at) AS solved_at
+				FROM attempts
+				WHERE game_id = ? AND correct
+				GROUP BY player_id
+			) s ON s.player_id = a.player_id AND a.submitted_at <= s.solved_at
+			WHERE a.game_id = ?
+			GROUP BY a.player_id
+		) AS t`, gameID, gameID).
+		Scan(&summary.AverageAttempts)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	result = database.Orm.Model(&entity.Attempt{}).
+		Where("game_id = ? AND NOT correct", gameID).
+		Select("blocks, COUNT(*) AS count").
+		Group("blocks").
+		Order("count DESC").
+		Limit(ATTEMPTS_SUMMARY_ANSWERS).
+		Scan(&summary.CommonWrongAnswers)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	result = database.Orm.Raw(`SELECT m.position - 1 AS position, AVG(CASE WHEN m.matched::boolean THEN 0 ELSE 1 END) AS error_rate
+		FROM attempts, jsonb_array_elements_text(matches) WITH ORDINALITY AS m(matched, position)
+		WHERE game_id = ? AND NOT correct
+		GROUP BY m.position
+		ORDER BY m.position`, gameID).
+		Scan(&summary.PositionErrors)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &summary, nil
+}
@@ -1,6 +1,7 @@
 package functionality
 
 import (
+	"backend/checkers"
 	"backend/database"
 	"backend/database/entity"
 	"backend/utils"
@@ -28,6 +29,23 @@ func BlocksOfAnswer(database *database.FinalTestinationDB, gameID uuid.UUID) ([]
 	return utils.Map(blocks, func(b entity.Block) string { return b.Content }), nil
 }
 
+// GameChecker returns the checker of the game, that accepts the solution defined by the order of the blocks
+// and the alternative solutions
+func GameChecker(database *database.FinalTestinationDB, gameID uuid.UUID) (checkers.Checker, error) {
+	var game entity.Game
+	result := database.Orm.Select("alternative_solutions, checker, checker_pattern").Where("id = ?", gameID).First(&game)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	solution, err := BlocksOfAnswer(database, gameID)
+	if err != nil {
+		return nil, err
+	}
+
+	return checkers.New(game.Checker, append([][]string{solution}, game.AlternativeSolutions...), game.CheckerPattern)
+}
+
 func GameGetByPreviousGame(database *database.FinalTestinationDB, previousGameId uuid.UUID) (uuid.UUID, error) {
 	var id_string string
 	result := database.Orm.Model(&entity.Game{}).Select("id").Where("game_order = (SELECT game_order FROM games WHERE id = ?)+1", previousGameId).First(&id_string)
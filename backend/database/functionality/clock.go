@@ -0,0 +1,328 @@
+package functionality
+
+import (
+	"backend/constants"
+	"backend/database"
+	"backend/database/entity"
+	"backend/utils"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrClockNotStarted = errors.New("the level was never opened")
+	ErrClockStopped    = errors.New("the level is already completed")
+	ErrClockPaused     = errors.New("the clock of the level is paused, resume it to play")
+)
+
+// TimeslotRemaining is the time left in a timeslot, or in a bucket, of the scoring policy of the level
+type TimeslotRemaining struct {
+	Timeslot         string  `json:"timeslot"`
+	Multiplier       float64 `json:"multiplier"`
+	RemainingSeconds int64   `json:"remaining_seconds"`
+}
+
+// ClockDTO is the state of the clock of a level, measured by the server
+type ClockDTO struct {
+	Running        bool       `json:"running"`
+	ElapsedSeconds int64      `json:"elapsed_seconds"`
+	FrozenUntil    *time.Time `json:"frozen_until"`
+	// Time left before the end of each timeslot, 0 once it is over
+	Timeslots []TimeslotRemaining `json:"timeslots"`
+}
+
+type clockWindow struct {
+	from time.Time
+	to   time.Time
+}
+
+func overlap(a clockWindow, b clockWindow) time.Duration {
+	from, to := a.from, a.to
+	if b.from.After(from) {
+		from = b.from
+	}
+	if b.to.Before(to) {
+		to = b.to
+	}
+	if to.Before(from) {
+		return 0
+	}
+	return to.Sub(from)
+}
+
+// clockElapsed replays the events, sorted by time: the clock runs from a start or a resume to a pause or the end,
+// and stops during the freezes. It also returns whether the clock is running and until when it is frozen.
+func clockElapsed(events []entity.ClockEvent, now time.Time) (time.Duration, bool, *time.Time) {
+	var runs []clockWindow
+	var freezes []clockWindow
+	var runningSince *time.Time
+	var frozenUntil *time.Time
+
+	for _, event := range events {
+		at := event.At
+		switch event.Kind {
+		case constants.CLOCK_EVENT_START, constants.CLOCK_EVENT_RESUME:
+			if runningSince == nil {
+				runningSince = &at
+			}
+		case constants.CLOCK_EVENT_PAUSE, constants.CLOCK_EVENT_END:
+			if runningSince != nil {
+				runs = append(runs, clockWindow{*runningSince, at})
+				runningSince = nil
+			}
+		case constants.CLOCK_EVENT_FREEZE:
+			if event.Until != nil {
+				freezes = append(freezes, clockWindow{at, *event.Until})
+				if !at.After(now) && now.Before(*event.Until) {
+					frozenUntil = event.Until
+				}
+			}
+		}
+	}
+	if runningSince != nil {
+		runs = append(runs, clockWindow{*runningSince, now})
+	}
+
+	// Overlapping freezes are merged, so that the same time is not subtracted twice
+	sort.Slice(freezes, func(i, j int) bool { return freezes[i].from.Before(freezes[j].from) })
+	merged := []clockWindow{}
+	for _, freeze := range freezes {
+		last := len(merged) - 1
+		if last >= 0 && !freeze.from.After(merged[last].to) {
+			if freeze.to.After(merged[last].to) {
+				merged[last].to = freeze.to
+			}
+			continue
+		}
+		merged = append(merged, freeze)
+	}
+
+	var elapsed time.Duration
+	for _, run := range runs {
+		elapsed += run.to.Sub(run.from)
+		for _, freeze := range merged {
+			elapsed -= overlap(run, freeze)
+		}
+	}
+
+	return elapsed, runningSince != nil, frozenUntil
+}
+
+func clockEvents(tx *gorm.DB, gameID uuid.UUID, playerID uuid.UUID) ([]entity.ClockEvent, error) {
+	events := []entity.ClockEvent{}
+	result := tx.Where("game_id = ? AND player_id = ?", gameID, playerID).Order("at").Find(&events)
+	return events, result.Error
+}
+
+func clockRecord(tx *gorm.DB, gameID uuid.UUID, playerID uuid.UUID, kind string, at time.Time, until *time.Time) error {
+	return tx.Create(&entity.ClockEvent{
+		Model:    utils.Model{ID: uuid.New().String()},
+		PlayerID: playerID.String(),
+		GameID:   gameID.String(),
+		Kind:     kind,
+		At:       at,
+		Until:    until,
+	}).Error
+}
+
+// clockLock locks the player game, so that concurrent requests record their events in order
+func clockLock(tx *gorm.DB, gameID uuid.UUID, playerID uuid.UUID) (*entity.PlayerGame, error) {
+	var playerGame entity.PlayerGame
+	result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("game_id = ? AND player_id = ?", gameID, playerID).
+		First(&playerGame)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, ErrClockNotStarted
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &playerGame, nil
+}
+
+// clockRun starts the clock the first time, or resumes it if it was paused
+func clockRun(tx *gorm.DB, gameID uuid.UUID, playerID uuid.UUID, now time.Time) error {
+	events, err := clockEvents(tx, gameID, playerID)
+	if err != nil {
+		return err
+	}
+	if len(events) == 0 {
+		return clockRecord(tx, gameID, playerID, constants.CLOCK_EVENT_START, now, nil)
+	}
+	if _, running, _ := clockElapsed(events, now); !running {
+		return clockRecord(tx, gameID, playerID, constants.CLOCK_EVENT_RESUME, now, nil)
+	}
+	return nil
+}
+
+// clockRunning returns ErrClockPaused if the clock of a level that is being played is paused. The levels started
+// before the clock existed have no events, and their clock is considered running.
+func clockRunning(tx *gorm.DB, gameID uuid.UUID, playerID uuid.UUID, now time.Time) error {
+	events, err := clockEvents(tx, gameID, playerID)
+	if err != nil {
+		return err
+	}
+	if _, running, _ := clockElapsed(events, now); len(events) > 0 && !running {
+		return ErrClockPaused
+	}
+	return nil
+}
+
+// clockStop records the end of the level and returns the time on the clock. The levels started before
+// the clock existed have no events, and their clock is considered running since the start time.
+// A paused clock cannot be stopped, otherwise the time spent looking at the paused level would not count.
+func clockStop(tx *gorm.DB, gameID uuid.UUID, playerID uuid.UUID, startTime time.Time, now time.Time) (time.Duration, error) {
+	events, err := clockEvents(tx, gameID, playerID)
+	if err != nil {
+		return 0, err
+	}
+	if len(events) == 0 {
+		events = append(events, entity.ClockEvent{Kind: constants.CLOCK_EVENT_START, At: startTime})
+	}
+	if _, running, _ := clockElapsed(events, now); !running {
+		return 0, ErrClockPaused
+	}
+	if err := clockRecord(tx, gameID, playerID, constants.CLOCK_EVENT_END, now, nil); err != nil {
+		return 0, err
+	}
+
+	elapsed, _, _ := clockElapsed(append(events, entity.ClockEvent{Kind: constants.CLOCK_EVENT_END, At: now}), now)
+	return elapsed, nil
+}
+
+// clockFreeze stops the clock for `duration`, starting now
+func clockFreeze(tx *gorm.DB, gameID uuid.UUID, playerID uuid.UUID, duration time.Duration) error {
+	now := time.Now()
+	until := now.Add(duration)
+	return clockRecord(tx, gameID, playerID, constants.CLOCK_EVENT_FREEZE, now, &until)
+}
+
+// clockElapsedOf returns the time on the clock of a level that is being played
+func clockElapsedOf(tx *gorm.DB, gameID uuid.UUID, playerID uuid.UUID, now time.Time) (time.Duration, error) {
+	events, err := clockEvents(tx, gameID, playerID)
+	if err != nil {
+		return 0, err
+	}
+	elapsed, _, _ := clockElapsed(events, now)
+	return elapsed, nil
+}
+
+func clockState(tx *gorm.DB, gameID uuid.UUID, playerID uuid.UUID, playerGame *entity.PlayerGame) (*ClockDTO, error) {
+	now := time.Now()
+	clock := ClockDTO{}
+
+	if playerGame.EndTime != nil && playerGame.SolveSeconds != nil {
+		clock.ElapsedSeconds = *playerGame.SolveSeconds
+	} else if playerGame.EndTime == nil {
+		events, err := clockEvents(tx, gameID, playerID)
+		if err != nil {
+			return nil, err
+		}
+		elapsed, running, frozenUntil := clockElapsed(events, now)
+		clock.ElapsedSeconds = int64(elapsed.Seconds())
+		clock.Running = running
+		clock.FrozenUntil = frozenUntil
+	}
+
+	var game entity.Game
+	result := tx.Select("perfect_timeslot, great_timeslot, medium_timeslot, not_so_good_timeslot, scoring").
+		Where("id = ?", gameID).
+		First(&game)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	clock.Timeslots = []TimeslotRemaining{}
+	for _, threshold := range game.Scoring.Thresholds(GameScoringLevel(&game)) {
+		remaining := max(threshold.UpTo-clock.ElapsedSeconds, 0)
+		clock.Timeslots = append(clock.Timeslots, TimeslotRemaining{
+			Timeslot:         threshold.Name,
+			Multiplier:       threshold.Multiplier,
+			RemainingSeconds: remaining,
+		})
+	}
+
+	return &clock, nil
+}
+
+// clockPause stops the clock if it is running
+func clockPause(tx *gorm.DB, gameID uuid.UUID, playerID uuid.UUID, now time.Time) error {
+	events, err := clockEvents(tx, gameID, playerID)
+	if err != nil {
+		return err
+	}
+	if _, running, _ := clockElapsed(events, now); running {
+		return clockRecord(tx, gameID, playerID, constants.CLOCK_EVENT_PAUSE, now, nil)
+	}
+	return nil
+}
+
+// clockChange applies `change` to the clock of a level that is being played, and returns its new state
+func clockChange(database *database.FinalTestinationDB, gameID uuid.UUID, playerID uuid.UUID, change func(tx *gorm.DB, gameID uuid.UUID, playerID uuid.UUID, now time.Time) error) (*ClockDTO, error) {
+	var clock *ClockDTO
+
+	err := database.Orm.Transaction(func(tx *gorm.DB) error {
+		playerGame, err := clockLock(tx, gameID, playerID)
+		if err != nil {
+			return err
+		}
+		if playerGame.EndTime != nil {
+			return ErrClockStopped
+		}
+
+		if err := change(tx, gameID, playerID, time.Now()); err != nil {
+			return err
+		}
+
+		clock, err = clockState(tx, gameID, playerID, playerGame)
+		return err
+	})
+
+	return clock, err
+}
+
+// ClockPause stops the clock while the player is away, pausing a clock that is not running does nothing
+func ClockPause(database *database.FinalTestinationDB, gameID uuid.UUID, playerID uuid.UUID) (*ClockDTO, error) {
+	return clockChange(database, gameID, playerID, clockPause)
+}
+
+// ClockResume restarts the clock when the player comes back, resuming a running clock does nothing
+func ClockResume(database *database.FinalTestinationDB, gameID uuid.UUID, playerID uuid.UUID) (*ClockDTO, error) {
+	return clockChange(database, gameID, playerID, clockRun)
+}
+
+// ClockCheckRunning returns ErrClockPaused if the player paused the level, so that it cannot be played without
+// the clock running. The levels that were never opened or are already completed have nothing to check.
+func ClockCheckRunning(database *database.FinalTestinationDB, gameID uuid.UUID, playerID uuid.UUID) error {
+	var playerGame entity.PlayerGame
+	result := database.Orm.Select("end_time").Where("game_id = ? AND player_id = ?", gameID, playerID).First(&playerGame)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if result.Error != nil {
+		return result.Error
+	}
+	if playerGame.EndTime != nil {
+		return nil
+	}
+
+	return clockRunning(database.Orm, gameID, playerID, time.Now())
+}
+
+func ClockGet(database *database.FinalTestinationDB, gameID uuid.UUID, playerID uuid.UUID) (*ClockDTO, error) {
+	var playerGame entity.PlayerGame
+	result := database.Orm.Where("game_id = ? AND player_id = ?", gameID, playerID).First(&playerGame)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, ErrClockNotStarted
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return clockState(database.Orm, gameID, playerID, &playerGame)
+}
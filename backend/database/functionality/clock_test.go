@@ -0,0 +1,126 @@
+package functionality
+
+import (
+	"backend/constants"
+	"backend/database/entity"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClockElapsed(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	at := func(seconds int) time.Time {
+		return start.Add(time.Duration(seconds) * time.Second)
+	}
+	event := func(kind string, seconds int) entity.ClockEvent {
+		return entity.ClockEvent{Kind: kind, At: at(seconds)}
+	}
+	freeze := func(from int, to int) entity.ClockEvent {
+		until := at(to)
+		return entity.ClockEvent{Kind: constants.CLOCK_EVENT_FREEZE, At: at(from), Until: &until}
+	}
+
+	tests := []struct {
+		description     string
+		events          []entity.ClockEvent
+		now             int
+		expectedElapsed int
+		expectedRunning bool
+		expectedFrozen  bool
+	}{
+		{
+			description:     "A clock that was never started",
+			events:          []entity.ClockEvent{},
+			now:             100,
+			expectedElapsed: 0,
+		},
+		{
+			description:     "A running clock",
+			events:          []entity.ClockEvent{event(constants.CLOCK_EVENT_START, 0)},
+			now:             100,
+			expectedElapsed: 100,
+			expectedRunning: true,
+		},
+		{
+			description: "The time while paused is not counted",
+			events: []entity.ClockEvent{
+				event(constants.CLOCK_EVENT_START, 0),
+				event(constants.CLOCK_EVENT_PAUSE, 60),
+				event(constants.CLOCK_EVENT_RESUME, 86400),
+			},
+			now:             86430,
+			expectedElapsed: 90,
+			expectedRunning: true,
+		},
+		{
+			description: "Pausing twice does not count the time in between",
+			events: []entity.ClockEvent{
+				event(constants.CLOCK_EVENT_START, 0),
+				event(constants.CLOCK_EVENT_PAUSE, 60),
+				event(constants.CLOCK_EVENT_PAUSE, 120),
+			},
+			now:             200,
+			expectedElapsed: 60,
+		},
+		{
+			description: "The clock stops at the end",
+			events: []entity.ClockEvent{
+				event(constants.CLOCK_EVENT_START, 0),
+				event(constants.CLOCK_EVENT_END, 50),
+			},
+			now:             1000,
+			expectedElapsed: 50,
+		},
+		{
+			description: "A freeze that is still going",
+			events: []entity.ClockEvent{
+				event(constants.CLOCK_EVENT_START, 0),
+				freeze(30, 90),
+			},
+			now:             60,
+			expectedElapsed: 30,
+			expectedRunning: true,
+			expectedFrozen:  true,
+		},
+		{
+			description: "A freeze that is over",
+			events: []entity.ClockEvent{
+				event(constants.CLOCK_EVENT_START, 0),
+				freeze(30, 90),
+			},
+			now:             120,
+			expectedElapsed: 60,
+			expectedRunning: true,
+		},
+		{
+			description: "Only the part of a freeze while the clock runs is subtracted",
+			events: []entity.ClockEvent{
+				event(constants.CLOCK_EVENT_START, 0),
+				freeze(30, 90),
+				event(constants.CLOCK_EVENT_PAUSE, 40),
+			},
+			now:             500,
+			expectedElapsed: 30,
+		},
+		{
+			description: "Overlapping freezes are subtracted once",
+			events: []entity.ClockEvent{
+				event(constants.CLOCK_EVENT_START, 0),
+				freeze(10, 40),
+				freeze(20, 50),
+			},
+			now:             100,
+			expectedElapsed: 60,
+			expectedRunning: true,
+		},
+	}
+
+	for _, test := range tests {
+		elapsed, running, frozenUntil := clockElapsed(test.events, at(test.now))
+		assert.Equal(t, time.Duration(test.expectedElapsed)*time.Second, elapsed, test.description)
+		assert.Equal(t, test.expectedRunning, running, test.description)
+		assert.Equal(t, test.expectedFrozen, frozenUntil != nil, test.description)
+	}
+}
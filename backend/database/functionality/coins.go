@@ -0,0 +1,146 @@
+package functionality
+
+import (
+	"backend/constants"
+	"backend/database"
+	"backend/database/entity"
+	"backend/utils"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var ErrInvalidCoinTransaction = errors.New("invalid coin transaction")
+
+// CoinHistoryDTO is a page of the coin ledger of a player, from the most recent transaction
+type CoinHistoryDTO struct {
+	Balance      int                      `json:"balance"`
+	CurrentPage  int                      `json:"currentPage"`
+	Pages        int                      `json:"pages"`
+	Transactions []entity.CoinTransaction `json:"transactions"`
+}
+
+// coinRecord appends a transaction to the ledger and applies it to the standings, `amount` is the number of coins
+// added or, for `spend`, removed
+func coinRecord(tx *gorm.DB, playerID uuid.UUID, kind string, amount int, reason string, gameID *uuid.UUID, reference *string) (*entity.CoinTransaction, error) {
+	if amount < 0 || reason == "" {
+		return nil, ErrInvalidCoinTransaction
+	}
+
+	switch kind {
+	case constants.COIN_SPEND:
+		amount = -amount
+	case constants.COIN_EARN, constants.COIN_GRANT, constants.COIN_REFUND:
+	default:
+		return nil, ErrInvalidCoinTransaction
+	}
+
+	transaction := entity.CoinTransaction{
+		Model:     utils.Model{ID: uuid.New().String()},
+		PlayerID:  playerID.String(),
+		Kind:      kind,
+		Amount:    amount,
+		Reason:    reason,
+		Reference: reference,
+		CreatedAt: time.Now(),
+	}
+	if gameID != nil {
+		id := gameID.String()
+		transaction.GameID = &id
+	}
+
+	if err := tx.Create(&transaction).Error; err != nil {
+		return nil, err
+	}
+	if err := standingsApply(tx, &transaction); err != nil {
+		return nil, err
+	}
+	return &transaction, nil
+}
+
+func coinBalance(tx *gorm.DB, playerID uuid.UUID) (int, error) {
+	var balance int
+	result := tx.Model(&entity.CoinTransaction{}).
+		Select("COALESCE(SUM(amount), 0)").
+		Where("player_id = ?", playerID).
+		Scan(&balance)
+	return balance, result.Error
+}
+
+// PlayerGetTotalCoins returns the balance of the coin ledger of a player
+func PlayerGetTotalCoins(db *database.FinalTestinationDB, playerID uuid.UUID) (int, error) {
+	return coinBalance(db.Orm, playerID)
+}
+
+// CoinHistory returns a page of the ledger of a player, `page` starts from 1
+func CoinHistory(db *database.FinalTestinationDB, playerID uuid.UUID, page int) (*CoinHistoryDTO, error) {
+	history := CoinHistoryDTO{Transactions: []entity.CoinTransaction{}}
+
+	balance, err := coinBalance(db.Orm, playerID)
+	if err != nil {
+		return nil, err
+	}
+	history.Balance = balance
+
+	var count int64
+	result := db.Orm.Model(&entity.CoinTransaction{}).Where("player_id = ?", playerID).Count(&count)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	history.Pages = int((count + constants.PAGE_SIZE - 1) / constants.PAGE_SIZE)
+	if page > history.Pages {
+		page = history.Pages
+	}
+	if page < 1 {
+		page = 1
+	}
+	history.CurrentPage = page
+
+	result = db.Orm.Where("player_id = ?", playerID).
+		Order("created_at DESC, id").
+		Limit(constants.PAGE_SIZE).
+		Offset((page - 1) * constants.PAGE_SIZE).
+		Find(&history.Transactions)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &history, nil
+}
+
+// CoinGrant gives coins to a player outside of the levels, as a grant or as a refund
+func CoinGrant(db *database.FinalTestinationDB, playerID uuid.UUID, kind string, amount int, reason string, gameID *uuid.UUID, reference *string) (*entity.CoinTransaction, error) {
+	if kind != constants.COIN_GRANT && kind != constants.COIN_REFUND {
+		return nil, ErrInvalidCoinTransaction
+	}
+
+	var count int64
+	result := db.Orm.Model(&entity.Player{}).Where("id = ?", playerID).Count(&count)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if count == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	if gameID != nil {
+		result = db.Orm.Model(&entity.Game{}).Where("id = ?", gameID).Count(&count)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if count == 0 {
+			return nil, ErrGameNotFound
+		}
+	}
+
+	var transaction *entity.CoinTransaction
+	err := db.Orm.Transaction(func(tx *gorm.DB) error {
+		var err error
+		transaction, err = coinRecord(tx, playerID, kind, amount, reason, gameID, reference)
+		return err
+	})
+	return transaction, err
+}
@@ -0,0 +1,93 @@
+package functionality
+
+import (
+	"backend/database"
+	"backend/database/entity"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var ErrInvalidDraft = errors.New("invalid draft")
+
+// draftAnswer checks that the answer has a position for every block of the solution and only uses the blocks
+// the player can see, each one as many times as it appears. The positions of the skeleton are cleared.
+func draftAnswer(blocks []entity.Block, eliminated []string, answer []*string) ([]*string, error) {
+	skeleton := map[uint]bool{}
+	available := map[string]int{}
+	solutionLength := 0
+	for _, block := range blocks {
+		if block.Order != nil {
+			solutionLength++
+		}
+		if block.Skeleton && block.Order != nil {
+			skeleton[*block.Order] = true
+		} else if !slices.Contains(eliminated, block.ID) {
+			available[block.Content]++
+		}
+	}
+
+	if len(answer) != solutionLength {
+		return nil, fmt.Errorf("%w: the answer must have %d positions", ErrInvalidDraft, solutionLength)
+	}
+
+	cleaned := make([]*string, len(answer))
+	for i, content := range answer {
+		if content == nil || skeleton[uint(i)] {
+			continue
+		}
+		if available[*content] == 0 {
+			return nil, fmt.Errorf("%w: block %q is not available", ErrInvalidDraft, *content)
+		}
+		available[*content]--
+		cleaned[i] = content
+	}
+	return cleaned, nil
+}
+
+// PlayerGameDraft returns the draft of a level that is still being played, nil if there is none
+// or if it does not match the blocks anymore, e.g. after some decoys were eliminated
+func PlayerGameDraft(game *entity.Game, pg *entity.PlayerGame) *entity.Draft {
+	if pg.Draft == nil || pg.EndTime != nil {
+		return nil
+	}
+	if _, err := draftAnswer(game.Blocks, pg.EliminatedBlocks, pg.Draft.Answer); err != nil {
+		return nil
+	}
+	return pg.Draft
+}
+
+// PlayerGameSaveDraft stores the answer the player is composing, replacing the previous draft
+func PlayerGameSaveDraft(db *database.FinalTestinationDB, gameID uuid.UUID, playerID uuid.UUID, answer []*string) (*entity.Draft, error) {
+	var draft *entity.Draft
+	err := db.Orm.Transaction(func(tx *gorm.DB) error {
+		pg, err := clockLock(tx, gameID, playerID)
+		if err != nil {
+			return err
+		}
+		if pg.EndTime != nil {
+			return ErrClockStopped
+		}
+		if err := clockRunning(tx, gameID, playerID, time.Now()); err != nil {
+			return err
+		}
+
+		var blocks []entity.Block
+		if err := tx.Where("game_id = ?", gameID).Find(&blocks).Error; err != nil {
+			return err
+		}
+		cleaned, err := draftAnswer(blocks, pg.EliminatedBlocks, answer)
+		if err != nil {
+			return err
+		}
+
+		draft = &entity.Draft{Answer: cleaned, UpdatedAt: time.Now()}
+		return tx.Model(&entity.PlayerGame{}).Where("game_id = ? AND player_id = ?", gameID, playerID).
+			Select("draft").Updates(entity.PlayerGame{Draft: draft}).Error
+	})
+	return draft, err
+}
@@ -0,0 +1,76 @@
+package functionality
+
+import (
+	"backend/database/entity"
+	"backend/utils"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDraftAnswer(t *testing.T) {
+	order := func(o uint) *uint { return &o }
+	content := func(c string) *string { return &c }
+
+	blocks := []entity.Block{
+		{Model: utils.Model{ID: "a"}, Content: "SELECT", Order: order(0), Skeleton: true},
+		{Model: utils.Model{ID: "b"}, Content: "*", Order: order(1)},
+		{Model: utils.Model{ID: "c"}, Content: "FROM", Order: order(2)},
+		{Model: utils.Model{ID: "d"}, Content: "DROP"},
+		{Model: utils.Model{ID: "e"}, Content: "*"},
+	}
+
+	tests := []struct {
+		description string
+		eliminated  []string
+		answer      []*string
+		expected    []*string
+		valid       bool
+	}{
+		{
+			description: "An empty answer",
+			answer:      []*string{nil, nil, nil},
+			expected:    []*string{nil, nil, nil},
+			valid:       true,
+		},
+		{
+			description: "The skeleton is cleared",
+			answer:      []*string{content("SELECT"), content("DROP"), content("*")},
+			expected:    []*string{nil, content("DROP"), content("*")},
+			valid:       true,
+		},
+		{
+			description: "A block that appears twice can be used twice",
+			answer:      []*string{nil, content("*"), content("*")},
+			expected:    []*string{nil, content("*"), content("*")},
+			valid:       true,
+		},
+		{
+			description: "A block used more times than it appears",
+			answer:      []*string{nil, content("FROM"), content("FROM")},
+		},
+		{
+			description: "A block that is not in the level",
+			answer:      []*string{nil, content("UNION"), nil},
+		},
+		{
+			description: "An eliminated decoy",
+			eliminated:  []string{"d"},
+			answer:      []*string{nil, content("DROP"), nil},
+		},
+		{
+			description: "Fewer positions than the solution",
+			answer:      []*string{nil, nil},
+		},
+	}
+
+	for _, test := range tests {
+		cleaned, err := draftAnswer(blocks, test.eliminated, test.answer)
+		if test.valid {
+			assert.NoError(t, err, test.description)
+			assert.Equal(t, test.expected, cleaned, test.description)
+		} else {
+			assert.ErrorIs(t, err, ErrInvalidDraft, test.description)
+		}
+	}
+}
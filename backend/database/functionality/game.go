@@ -1,13 +1,18 @@
 package functionality
 
 import (
+	"backend/checkers"
 	"backend/database"
 	"backend/database/entity"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 func GameGetById(database *database.FinalTestinationDB, gameID uuid.UUID) (*entity.Game, error) {
@@ -16,10 +21,11 @@ func GameGetById(database *database.FinalTestinationDB, gameID uuid.UUID) (*enti
 	result := database.Orm.Model(&entity.Game{}).Select("*").Where("id = ?", gameID).First(&game)
 	res_blocks := database.Orm.Model(&entity.Block{}).Select("*").Where("game_id = ?", gameID).Scan(&blocks)
 
-	err := result.Error
-	err2 := res_blocks.Error
-	if err != nil || err2 != nil {
-		return nil, err
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if res_blocks.Error != nil {
+		return nil, res_blocks.Error
 	}
 	game.Blocks = blocks
 	return &game, nil
@@ -28,19 +34,391 @@ func GameGetById(database *database.FinalTestinationDB, gameID uuid.UUID) (*enti
 func GameSetStartTime(database *database.FinalTestinationDB, gameID uuid.UUID, playerID uuid.UUID) (*entity.PlayerGame, error) {
 	var playerGame *entity.PlayerGame
 
-	result := database.Orm.First(&playerGame, "player_id = ? AND game_id = ?", playerID, gameID)
+	err := database.Orm.First(&playerGame, "player_id = ? AND game_id = ?", playerID, gameID).Error
 
-	if result.Error != nil {
+	if err != nil {
 
 		// if the player never played this game, then the playerGame will be nil. In this case, we create a new playerGame
 		// with the provided values. Other values will be filled with default values by the DB.
-		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
 			playerGame.PlayerID = playerID.String()
 			playerGame.GameID = gameID.String()
 			playerGame.StartTime = time.Now()
-			result = database.Orm.Create(&playerGame)
+			playerGame.EliminatedBlocks = []string{}
+			// playing the first level adds the player to the standings
+			err = database.Orm.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Create(&playerGame).Error; err != nil {
+					return err
+				}
+				return standingsJoin(tx, playerID)
+			})
+		}
+	}
+	if err != nil {
+		return playerGame, err
+	}
+
+	// Opening a level that is not completed yet starts or resumes its clock
+	if playerGame.EndTime == nil {
+		err = database.Orm.Transaction(func(tx *gorm.DB) error {
+			if _, err := clockLock(tx, gameID, playerID); err != nil {
+				return err
+			}
+			return clockRun(tx, gameID, playerID, time.Now())
+		})
+		if err != nil {
+			return playerGame, err
+		}
+	}
+
+	return playerGame, nil
+}
+
+var (
+	ErrInvalidGame     = errors.New("invalid game")
+	ErrGameOrderTaken  = errors.New("game order already taken")
+	ErrGameHasPlayers  = errors.New("game has already been played")
+	ErrGameNotFound    = errors.New("game not found")
+	errInvalidBlockSet = fmt.Errorf("%w: invalid blocks", ErrInvalidGame)
+)
+
+func GameGetAll(database *database.FinalTestinationDB) ([]entity.Game, error) {
+	var games []entity.Game
+	result := database.Orm.Order("game_order").Find(&games)
+	return games, result.Error
+}
+
+// GameValidate checks the fields of a game that cannot be enforced by the database.
+// The blocks are validated only if present, so that a game can be edited without resending them.
+func GameValidate(game *entity.Game) error {
+	if strings.TrimSpace(game.Title) == "" {
+		return fmt.Errorf("%w: title is required", ErrInvalidGame)
+	}
+	if game.GameOrder < 1 {
+		return fmt.Errorf("%w: game_order must be at least 1", ErrInvalidGame)
+	}
+	if game.MaxScore < 0 || game.WrongAttemptCost < 0 ||
+		game.HintSolutionPrice < 0 ||
+		game.TimeFreezePrice < 0 || game.TimeFreezeDuration < 0 ||
+		game.EliminateDecoysCount < 0 || game.EliminateDecoysPrice < 0 {
+		return fmt.Errorf("%w: scores, prices and durations cannot be negative", ErrInvalidGame)
+	}
+	for i, hint := range game.TextualHints {
+		if strings.TrimSpace(hint.Content) == "" {
+			return fmt.Errorf("%w: textual hint %d is empty", ErrInvalidGame, i)
+		}
+		if hint.Price < 0 {
+			return fmt.Errorf("%w: scores, prices and durations cannot be negative", ErrInvalidGame)
+		}
+	}
+	if game.PerfectTimeslot > game.GreatTimeslot ||
+		game.GreatTimeslot > game.MediumTimeslot ||
+		game.MediumTimeslot > game.NotSoGoodTimeslot {
+		return fmt.Errorf("%w: timeslots must be in increasing order", ErrInvalidGame)
+	}
+
+	if err := checkers.Validate(game.Checker, game.CheckerPattern); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidGame, err)
+	}
+	if err := game.Scoring.Validate(); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidGame, err)
+	}
+
+	if game.Blocks != nil {
+		if err := GameValidateBlocks(game.Blocks); err != nil {
+			return err
+		}
+		return GameValidateSolutions(game.Blocks, game.AlternativeSolutions)
+	}
+	return nil
+}
+
+// GameValidateBlocks checks that every skeleton block has an order and that the orders of the
+// solution blocks go from 0 to len(solution)-1 without gaps or duplicates.
+func GameValidateBlocks(blocks []entity.Block) error {
+	seen := map[uint]bool{}
+
+	for _, block := range blocks {
+		if block.Content == "" {
+			return fmt.Errorf("%w: block content cannot be empty", errInvalidBlockSet)
+		}
+		if block.Order == nil {
+			if block.Skeleton {
+				return fmt.Errorf("%w: skeleton block %q must have an order", errInvalidBlockSet, block.Content)
+			}
+			continue
+		}
+		if seen[*block.Order] {
+			return fmt.Errorf("%w: order %d is used more than once", errInvalidBlockSet, *block.Order)
+		}
+		seen[*block.Order] = true
+	}
+
+	for i := 0; i < len(seen); i++ {
+		if !seen[uint(i)] {
+			return fmt.Errorf("%w: solution orders must be contiguous from 0, %d is missing", errInvalidBlockSet, i)
+		}
+	}
+
+	return nil
+}
+
+// GameValidateSolutions checks that every alternative solution could be built by the player: it has the
+// same length as the solution, keeps the skeleton blocks in place and uses each of the other blocks at most once.
+func GameValidateSolutions(blocks []entity.Block, alternatives [][]string) error {
+	skeleton := map[uint]string{}
+	available := map[string]int{}
+	length := 0
+	for _, block := range blocks {
+		if block.Order != nil {
+			length++
+		}
+		if block.Skeleton && block.Order != nil {
+			skeleton[*block.Order] = block.Content
+		} else {
+			available[block.Content]++
+		}
+	}
+
+	for i, alternative := range alternatives {
+		if len(alternative) != length {
+			return fmt.Errorf("%w: alternative solution %d must have %d blocks", ErrInvalidGame, i, length)
+		}
+
+		used := map[string]int{}
+		for position, content := range alternative {
+			if skeletonContent, ok := skeleton[uint(position)]; ok {
+				if content != skeletonContent {
+					return fmt.Errorf("%w: alternative solution %d must have the skeleton block %q in position %d", ErrInvalidGame, i, skeletonContent, position)
+				}
+				continue
+			}
+			used[content]++
+			if used[content] > available[content] {
+				return fmt.Errorf("%w: alternative solution %d uses %q more times than there are blocks", ErrInvalidGame, i, content)
+			}
+		}
+	}
+
+	return nil
+}
+
+func gameCheckOrderAvailable(tx *gorm.DB, gameOrder int, gameID string) error {
+	var count int64
+	result := tx.Model(&entity.Game{}).Where("game_order = ? AND id <> ?", gameOrder, gameID).Count(&count)
+	if result.Error != nil {
+		return result.Error
+	}
+	if count > 0 {
+		return ErrGameOrderTaken
+	}
+	return nil
+}
+
+// blockKey identifies a block by what the player sees of it
+func blockKey(block entity.Block) string {
+	order := "decoy"
+	if block.Order != nil {
+		order = fmt.Sprint(*block.Order)
+	}
+	return fmt.Sprintf("%s|%t|%s", order, block.Skeleton, block.Content)
+}
+
+// assignBlockIDs gives the new blocks of a game the IDs of the existing blocks with the same content, order and
+// skeleton flag, so that what refers to them, like the decoys removed by the eliminate hints, survives an update.
+// The other blocks get a new ID.
+func assignBlockIDs(existing []entity.Block, blocks []entity.Block) {
+	available := map[string][]string{}
+	for _, block := range existing {
+		key := blockKey(block)
+		available[key] = append(available[key], block.ID)
+	}
+	for i := range blocks {
+		key := blockKey(blocks[i])
+		if ids := available[key]; len(ids) > 0 {
+			blocks[i].ID = ids[0]
+			available[key] = ids[1:]
+		} else {
+			blocks[i].ID = uuid.New().String()
 		}
 	}
+}
+
+// gameOrderConflict turns the violation of the unique index on the order of the games, hit when a concurrent save
+// took the order after gameCheckOrderAvailable, into ErrGameOrderTaken
+func gameOrderConflict(err error) error {
+	var pgErr *pgconn.PgError
+	// 23505 is unique_violation
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "idx_games_game_order" {
+		return ErrGameOrderTaken
+	}
+	return err
+}
+
+func gameReplaceBlocks(tx *gorm.DB, gameID string, blocks []entity.Block) error {
+	var existing []entity.Block
+	if err := tx.Where("game_id = ?", gameID).Find(&existing).Error; err != nil {
+		return err
+	}
+	if result := tx.Where("game_id = ?", gameID).Delete(&entity.Block{}); result.Error != nil {
+		return result.Error
+	}
+	if len(blocks) == 0 {
+		return nil
+	}
+
+	assignBlockIDs(existing, blocks)
+	for i := range blocks {
+		blocks[i].GameID = gameID
+	}
+	return tx.Omit(clause.Associations).Create(&blocks).Error
+}
+
+func gameCreate(tx *gorm.DB, game *entity.Game) error {
+	if err := gameCheckOrderAvailable(tx, game.GameOrder, game.ID); err != nil {
+		return err
+	}
+	if game.AlternativeSolutions == nil {
+		game.AlternativeSolutions = [][]string{}
+	}
+	if game.TextualHints == nil {
+		game.TextualHints = []entity.TextualHint{}
+	}
+	if game.Checker == "" {
+		game.Checker = checkers.CHECKER_EXACT
+	}
+	if err := tx.Omit(clause.Associations).Create(game).Error; err != nil {
+		return gameOrderConflict(err)
+	}
+	return gameReplaceBlocks(tx, game.ID, game.Blocks)
+}
+
+func gameUpdate(tx *gorm.DB, game *entity.Game) error {
+	if err := gameCheckOrderAvailable(tx, game.GameOrder, game.ID); err != nil {
+		return err
+	}
+	if game.AlternativeSolutions == nil {
+		game.AlternativeSolutions = [][]string{}
+	}
+	if game.TextualHints == nil {
+		game.TextualHints = []entity.TextualHint{}
+	}
+	if game.Checker == "" {
+		game.Checker = checkers.CHECKER_EXACT
+	}
+	result := tx.Model(game).Select("*").Omit(clause.Associations).Updates(game)
+	if result.Error != nil {
+		return gameOrderConflict(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrGameNotFound
+	}
+	if game.Blocks == nil {
+		// The alternative solutions may have changed, so they are checked against the current blocks
+		var blocks []entity.Block
+		if err := tx.Where("game_id = ?", game.ID).Find(&blocks).Error; err != nil {
+			return err
+		}
+		return GameValidateSolutions(blocks, game.AlternativeSolutions)
+	}
+	return gameReplaceBlocks(tx, game.ID, game.Blocks)
+}
 
-	return playerGame, result.Error
+func GameCreate(database *database.FinalTestinationDB, game *entity.Game) error {
+	if err := GameValidate(game); err != nil {
+		return err
+	}
+	game.ID = uuid.New().String()
+
+	return database.Orm.Transaction(func(tx *gorm.DB) error {
+		return gameCreate(tx, game)
+	})
+}
+
+// GameUpdate overwrites every field of the game. The blocks are replaced only if `game.Blocks` is not nil.
+func GameUpdate(database *database.FinalTestinationDB, gameID uuid.UUID, game *entity.Game) error {
+	if err := GameValidate(game); err != nil {
+		return err
+	}
+	game.ID = gameID.String()
+
+	return database.Orm.Transaction(func(tx *gorm.DB) error {
+		return gameUpdate(tx, game)
+	})
+}
+
+// GameImport creates the game with the given ID, or overwrites it together with its blocks
+// if it already exists, so that importing the same level twice leaves the database unchanged.
+// It returns whether the game was created.
+func GameImport(database *database.FinalTestinationDB, game *entity.Game) (bool, error) {
+	if _, err := uuid.Parse(game.ID); err != nil {
+		return false, fmt.Errorf("%w: the ID must be a valid UUID", ErrInvalidGame)
+	}
+	if game.Blocks == nil {
+		game.Blocks = []entity.Block{}
+	}
+	if err := GameValidate(game); err != nil {
+		return false, err
+	}
+
+	created := false
+	err := database.Orm.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&entity.Game{}).Where("id = ?", game.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			created = true
+			return gameCreate(tx, game)
+		}
+		return gameUpdate(tx, game)
+	})
+
+	return created, err
+}
+
+func GameReplaceBlocks(database *database.FinalTestinationDB, gameID uuid.UUID, blocks []entity.Block) error {
+	if err := GameValidateBlocks(blocks); err != nil {
+		return err
+	}
+
+	return database.Orm.Transaction(func(tx *gorm.DB) error {
+		var game entity.Game
+		result := tx.Select("alternative_solutions").Where("id = ?", gameID).First(&game)
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return ErrGameNotFound
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+		if err := GameValidateSolutions(blocks, game.AlternativeSolutions); err != nil {
+			return err
+		}
+		return gameReplaceBlocks(tx, gameID.String(), blocks)
+	})
+}
+
+// GameDelete removes a game and its blocks. Games that have already been played cannot be
+// deleted, since that would also remove the coins that players earned with them.
+func GameDelete(database *database.FinalTestinationDB, gameID uuid.UUID) error {
+	return database.Orm.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&entity.PlayerGame{}).Where("game_id = ?", gameID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrGameHasPlayers
+		}
+		if err := tx.Where("game_id = ?", gameID).Delete(&entity.Block{}).Error; err != nil {
+			return err
+		}
+		result := tx.Where("id = ?", gameID).Delete(&entity.Game{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrGameNotFound
+		}
+		return nil
+	})
 }
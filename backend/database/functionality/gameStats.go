@@ -0,0 +1,149 @@
+package functionality
+
+import (
+	"backend/constants"
+	"backend/database"
+	"backend/database/entity"
+	"backend/scoring"
+	"math"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+type SolveTimeStats struct {
+	Median float64 `json:"median"`
+	P75    float64 `json:"p75"`
+	P90    float64 `json:"p90"`
+}
+
+// TimeslotCount is how many completions fall in a timeslot, or in a bucket, of the scoring policy of the game.
+// The last one is `late`, slower than every other and without `up_to`.
+type TimeslotCount struct {
+	Timeslot    string `json:"timeslot"`
+	UpTo        *int64 `json:"up_to"`
+	Completions int    `json:"completions"`
+}
+
+// The completions slower than every timeslot
+const TIMESLOT_LATE = "late"
+
+// HintRates is the share of the players that bought each hint while playing the level
+type HintRates struct {
+	Textual   float64 `json:"textual"`
+	Fill      float64 `json:"fill"`
+	Freeze    float64 `json:"freeze"`
+	Eliminate float64 `json:"eliminate"`
+}
+
+// GameStatsDTO describes how the players are doing on a game, so that authors can tune its timeslots and hint prices
+type GameStatsDTO struct {
+	Players        int            `json:"players"`
+	Completed      int            `json:"completed"`
+	CompletionRate float64        `json:"completion_rate"`
+	SolveTime      SolveTimeStats `json:"solve_time_seconds"`
+	// Not a column, so that gorm does not take it for a relation when scanning the counts
+	Timeslots            []TimeslotCount `gorm:"-" json:"timeslots"`
+	HintRates            HintRates       `json:"hint_rates"`
+	AverageWrongAttempts float64         `json:"average_wrong_attempts"`
+}
+
+// timeslotDistribution counts the solve times that fall within each threshold of a scoring policy
+func timeslotDistribution(thresholds []scoring.Threshold, times []int64) []TimeslotCount {
+	distribution := make([]TimeslotCount, 0, len(thresholds)+1)
+	for _, threshold := range thresholds {
+		upTo := threshold.UpTo
+		distribution = append(distribution, TimeslotCount{Timeslot: threshold.Name, UpTo: &upTo})
+	}
+	distribution = append(distribution, TimeslotCount{Timeslot: TIMESLOT_LATE})
+
+	for _, time := range times {
+		distribution[scoring.ThresholdIndex(thresholds, time)].Completions++
+	}
+	return distribution
+}
+
+// percentile interpolates between the closest ranks like `percentile_cont`, `sorted` must be in increasing order
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := p * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
+
+// hintUsedQuery finds the hints of a type, passed as argument, bought while playing the level of the current player game
+const hintUsedQuery = `SELECT 1 FROM hint_usages
+	WHERE hint_usages.player_id = player_games.player_id AND hint_usages.game_id = player_games.game_id
+		AND hint_usages.hint_type = ? AND NOT hint_usages.replay`
+
+func GameStats(database *database.FinalTestinationDB, gameID uuid.UUID) (*GameStatsDTO, error) {
+	var stats GameStatsDTO
+
+	result := database.Orm.Model(&entity.PlayerGame{}).
+		Where("game_id = ?", gameID).
+		Select(`COUNT(*) AS players,
+			COUNT(*) FILTER (WHERE end_time IS NOT NULL) AS completed,
+			COALESCE(AVG(attempts), 0) AS average_wrong_attempts`).
+		Scan(&stats)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	result = database.Orm.Model(&entity.PlayerGame{}).
+		Where("game_id = ?", gameID).
+		Select(`COALESCE(AVG(CASE WHEN EXISTS (`+hintUsedQuery+`) THEN 1 ELSE 0 END), 0) AS textual,
+			COALESCE(AVG(CASE WHEN EXISTS (`+hintUsedQuery+`) THEN 1 ELSE 0 END), 0) AS fill,
+			COALESCE(AVG(CASE WHEN EXISTS (`+hintUsedQuery+`) THEN 1 ELSE 0 END), 0) AS freeze,
+			COALESCE(AVG(CASE WHEN EXISTS (`+hintUsedQuery+`) THEN 1 ELSE 0 END), 0) AS eliminate`,
+			constants.HINT_TEXTUAL, constants.HINT_FILL, constants.HINT_FREEZE, constants.HINT_ELIMINATE).
+		Scan(&stats.HintRates)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	if stats.Players > 0 {
+		stats.CompletionRate = float64(stats.Completed) / float64(stats.Players)
+	}
+
+	// The solve time is the one on the clock, so that the timeslots match the scores.
+	// The levels completed before the clock existed fall back to the time between start and end.
+	var game entity.Game
+	result = database.Orm.Select("perfect_timeslot, great_timeslot, medium_timeslot, not_so_good_timeslot, scoring").
+		Where("id = ?", gameID).
+		First(&game)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	timesUsed := []int64{}
+	result = database.Orm.Model(&entity.PlayerGame{}).
+		Joins("JOIN games ON player_games.game_id = games.id").
+		Where("player_games.game_id = ? AND player_games.end_time IS NOT NULL", gameID).
+		Select(`COALESCE(player_games.solve_seconds,
+				EXTRACT(EPOCH FROM player_games.end_time)::bigint - EXTRACT(EPOCH FROM player_games.start_time)::bigint
+				- CASE WHEN EXISTS (`+hintUsedQuery+`) THEN games.time_freeze_duration ELSE 0 END) AS time_used`, constants.HINT_FREEZE).
+		Scan(&timesUsed)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	times := make([]float64, 0, len(timesUsed))
+	for _, timeUsed := range timesUsed {
+		times = append(times, float64(timeUsed))
+	}
+	sort.Float64s(times)
+
+	stats.SolveTime = SolveTimeStats{
+		Median: percentile(times, 0.5),
+		P75:    percentile(times, 0.75),
+		P90:    percentile(times, 0.9),
+	}
+	// the completions are bucketed like the scoring policy of the game scores them
+	stats.Timeslots = timeslotDistribution(game.Scoring.Thresholds(GameScoringLevel(&game)), timesUsed)
+
+	return &stats, nil
+}
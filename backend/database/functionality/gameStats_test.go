@@ -0,0 +1,50 @@
+package functionality
+
+import (
+	"backend/scoring"
+	"backend/utils"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeslotDistribution(t *testing.T) {
+	counts := func(distribution []TimeslotCount) map[string]int {
+		result := map[string]int{}
+		for _, timeslot := range distribution {
+			result[timeslot.Timeslot] = timeslot.Completions
+		}
+		return result
+	}
+	upTos := func(distribution []TimeslotCount) []*int64 {
+		return utils.Map(distribution, func(timeslot TimeslotCount) *int64 { return timeslot.UpTo })
+	}
+	seconds := func(value int64) *int64 { return &value }
+	level := scoring.Level{MaxScore: 100, Timeslots: [4]int64{60, 120, 180, 240}}
+	times := []int64{0, 59, 60, 200, 1000}
+
+	legacy := timeslotDistribution((&scoring.Policy{}).Thresholds(level), times)
+	assert.Equal(t, map[string]int{"perfect": 2, "great": 1, "medium": 0, "not_so_good": 1, TIMESLOT_LATE: 1}, counts(legacy),
+		"The default policy counts the completions in the timeslots of the game")
+	assert.Equal(t, []*int64{seconds(60), seconds(120), seconds(180), seconds(240), nil}, upTos(legacy),
+		"Every timeslot ends at its own threshold, the late completions have no end")
+
+	buckets := scoring.Policy{Curve: scoring.CURVE_BUCKETS, Buckets: []scoring.Bucket{{UpTo: 30, Multiplier: 1}, {UpTo: 600, Multiplier: 0.5}}}
+	distribution := timeslotDistribution(buckets.Thresholds(level), times)
+	assert.Equal(t, []string{"bucket_1", "bucket_2", TIMESLOT_LATE}, utils.Map(distribution, func(timeslot TimeslotCount) string { return timeslot.Timeslot }),
+		"A policy with buckets counts the completions in its buckets, not in the timeslots of the game")
+	assert.Equal(t, map[string]int{"bucket_1": 1, "bucket_2": 3, TIMESLOT_LATE: 1}, counts(distribution))
+	assert.Equal(t, []*int64{seconds(30), seconds(600), nil}, upTos(distribution))
+
+	decay := scoring.Policy{Curve: scoring.CURVE_DECAY, GraceSeconds: 60, HalfLifeSeconds: 60}
+	assert.Equal(t, map[string]int{scoring.THRESHOLD_GRACE: 3, TIMESLOT_LATE: 2}, counts(timeslotDistribution(decay.Thresholds(level), times)),
+		"A decay policy counts the completions within its grace period")
+}
+
+func TestPercentile(t *testing.T) {
+	assert.Equal(t, 0.0, percentile([]float64{}, 0.5), "The percentile of no values")
+	assert.Equal(t, 42.0, percentile([]float64{42}, 0.9), "The percentile of a single value")
+	assert.Equal(t, 2.5, percentile([]float64{1, 2, 3, 4}, 0.5), "The median of an even number of values")
+	assert.Equal(t, 3.0, percentile([]float64{1, 2, 3, 4, 5}, 0.5), "The median of an odd number of values")
+	assert.InDelta(t, 4.6, percentile([]float64{1, 2, 3, 4, 5}, 0.9), 1e-9, "The values are interpolated")
+}
@@ -0,0 +1,256 @@
+package functionality
+
+import (
+	"backend/database/entity"
+	"backend/utils"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+)
+
+func order(o uint) *uint {
+	return &o
+}
+
+func TestGameValidateBlocks(t *testing.T) {
+	tests := []struct {
+		description string
+		blocks      []entity.Block
+		valid       bool
+	}{
+		{
+			description: "Solution blocks, skeleton blocks and decoys are valid",
+			blocks: []entity.Block{
+				{Content: "<iframe", Order: order(0)},
+				{Content: "src=\"http://", Order: order(1), Skeleton: true},
+				{Content: "evilcompany", Order: order(2)},
+				{Content: "goodcompany"},
+			},
+			valid: true,
+		},
+		{
+			description: "An empty list of blocks is valid",
+			blocks:      []entity.Block{},
+			valid:       true,
+		},
+		{
+			description: "A skeleton block without order is invalid",
+			blocks: []entity.Block{
+				{Content: "<iframe", Order: order(0)},
+				{Content: "src=\"http://", Skeleton: true},
+			},
+			valid: false,
+		},
+		{
+			description: "Solution orders that do not start from 0 are invalid",
+			blocks: []entity.Block{
+				{Content: "<iframe", Order: order(1)},
+				{Content: "evilcompany", Order: order(2)},
+			},
+			valid: false,
+		},
+		{
+			description: "Solution orders with a gap are invalid",
+			blocks: []entity.Block{
+				{Content: "<iframe", Order: order(0)},
+				{Content: "evilcompany", Order: order(2)},
+			},
+			valid: false,
+		},
+		{
+			description: "Duplicated solution orders are invalid",
+			blocks: []entity.Block{
+				{Content: "<iframe", Order: order(0)},
+				{Content: "evilcompany", Order: order(0)},
+			},
+			valid: false,
+		},
+		{
+			description: "Blocks without content are invalid",
+			blocks: []entity.Block{
+				{Content: "", Order: order(0)},
+			},
+			valid: false,
+		},
+	}
+
+	for _, test := range tests {
+		err := GameValidateBlocks(test.blocks)
+		if test.valid {
+			assert.NoErrorf(t, err, test.description)
+		} else {
+			assert.Truef(t, errors.Is(err, ErrInvalidGame), test.description)
+		}
+	}
+}
+
+func TestGameValidateSolutions(t *testing.T) {
+	blocks := []entity.Block{
+		{Content: "<iframe", Order: order(0), Skeleton: true},
+		{Content: "width=0", Order: order(1)},
+		{Content: "height=0", Order: order(2)},
+		{Content: "/>", Order: order(3), Skeleton: true},
+		{Content: "border=0"},
+	}
+
+	tests := []struct {
+		description  string
+		alternatives [][]string
+		valid        bool
+	}{
+		{
+			description:  "No alternative solutions is valid",
+			alternatives: nil,
+			valid:        true,
+		},
+		{
+			description:  "Swapping blocks and using decoys is valid",
+			alternatives: [][]string{{"<iframe", "height=0", "width=0", "/>"}, {"<iframe", "border=0", "width=0", "/>"}},
+			valid:        true,
+		},
+		{
+			description:  "An alternative solution with a different length is invalid",
+			alternatives: [][]string{{"<iframe", "height=0", "/>"}},
+			valid:        false,
+		},
+		{
+			description:  "Moving a skeleton block is invalid",
+			alternatives: [][]string{{"width=0", "<iframe", "height=0", "/>"}},
+			valid:        false,
+		},
+		{
+			description:  "Using a block twice is invalid",
+			alternatives: [][]string{{"<iframe", "width=0", "width=0", "/>"}},
+			valid:        false,
+		},
+		{
+			description:  "Using a block that does not exist is invalid",
+			alternatives: [][]string{{"<iframe", "width=0", "<script", "/>"}},
+			valid:        false,
+		},
+	}
+
+	for _, test := range tests {
+		err := GameValidateSolutions(blocks, test.alternatives)
+		if test.valid {
+			assert.NoErrorf(t, err, test.description)
+		} else {
+			assert.Truef(t, errors.Is(err, ErrInvalidGame), test.description)
+		}
+	}
+}
+
+func TestGameValidate(t *testing.T) {
+	validGame := func() entity.Game {
+		return entity.Game{
+			Title:             "XSS Attack",
+			GameOrder:         1,
+			MaxScore:          100,
+			PerfectTimeslot:   60,
+			GreatTimeslot:     120,
+			MediumTimeslot:    180,
+			NotSoGoodTimeslot: 240,
+		}
+	}
+
+	tests := []struct {
+		description string
+		edit        func(*entity.Game)
+		valid       bool
+	}{
+		{
+			description: "A complete game is valid",
+			edit:        func(g *entity.Game) {},
+			valid:       true,
+		},
+		{
+			description: "A game without title is invalid",
+			edit:        func(g *entity.Game) { g.Title = " " },
+			valid:       false,
+		},
+		{
+			description: "A game with order 0 is invalid",
+			edit:        func(g *entity.Game) { g.GameOrder = 0 },
+			valid:       false,
+		},
+		{
+			description: "A game with a negative price is invalid",
+			edit:        func(g *entity.Game) { g.TextualHints = []entity.TextualHint{{Content: "hint", Price: -1}} },
+			valid:       false,
+		},
+		{
+			description: "A game with an empty textual hint is invalid",
+			edit:        func(g *entity.Game) { g.TextualHints = []entity.TextualHint{{Content: "hint"}, {Content: " "}} },
+			valid:       false,
+		},
+		{
+			description: "A game with decreasing timeslots is invalid",
+			edit:        func(g *entity.Game) { g.GreatTimeslot = 30 },
+			valid:       false,
+		},
+		{
+			description: "A game with an unknown checker is invalid",
+			edit:        func(g *entity.Game) { g.Checker = "fuzzy" },
+			valid:       false,
+		},
+		{
+			description: "A game with a regex checker needs a pattern",
+			edit:        func(g *entity.Game) { g.Checker = "regex" },
+			valid:       false,
+		},
+		{
+			description: "A game with invalid blocks is invalid",
+			edit: func(g *entity.Game) {
+				g.Blocks = []entity.Block{{Content: "<iframe", Order: order(1)}}
+			},
+			valid: false,
+		},
+	}
+
+	for _, test := range tests {
+		game := validGame()
+		test.edit(&game)
+		err := GameValidate(&game)
+		if test.valid {
+			assert.NoErrorf(t, err, test.description)
+		} else {
+			assert.Truef(t, errors.Is(err, ErrInvalidGame), test.description)
+		}
+	}
+}
+
+func TestAssignBlockIDs(t *testing.T) {
+	existing := []entity.Block{
+		{Model: utils.Model{ID: "iframe"}, Content: "<iframe", Order: order(0)},
+		{Model: utils.Model{ID: "good"}, Content: "goodcompany"},
+		{Model: utils.Model{ID: "half"}, Content: "50%"},
+		{Model: utils.Model{ID: "quarter"}, Content: "25%"},
+	}
+	blocks := []entity.Block{
+		{Content: "<iframe", Order: order(0)},
+		{Content: "goodcompany"},
+		{Content: "50%", Order: order(1)},
+		{Content: "25%"},
+		{Content: "25%"},
+	}
+
+	assignBlockIDs(existing, blocks)
+	assert.Equal(t, "iframe", blocks[0].ID, "An unchanged block keeps its ID")
+	assert.Equal(t, "good", blocks[1].ID, "An unchanged decoy keeps its ID")
+	assert.NotEqual(t, "half", blocks[2].ID, "A decoy that became part of the solution is a new block")
+	assert.Equal(t, "quarter", blocks[3].ID)
+	assert.NotEqual(t, "quarter", blocks[4].ID, "An ID is given to a single block")
+	assert.NotEmpty(t, blocks[4].ID)
+}
+
+func TestGameOrderConflict(t *testing.T) {
+	taken := &pgconn.PgError{Code: "23505", ConstraintName: "idx_games_game_order"}
+	assert.ErrorIs(t, gameOrderConflict(fmt.Errorf("saving the game: %w", taken)), ErrGameOrderTaken, "Two games with the same order")
+
+	other := &pgconn.PgError{Code: "23505", ConstraintName: "games_pkey"}
+	assert.Equal(t, other, gameOrderConflict(other), "Another unique index")
+	assert.Nil(t, gameOrderConflict(nil))
+}
@@ -0,0 +1,123 @@
+package functionality
+
+import (
+	"backend/constants"
+	"backend/database"
+	"backend/database/entity"
+	"backend/utils"
+	"math/rand"
+	"slices"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// HintsSpent is what a player paid for each type of hint while playing a level, nil if the hint was not bought.
+// The hints used after completing the level are free and are not counted.
+type HintsSpent struct {
+	Textual      *int  `json:"textual"`
+	Fill         *int  `json:"fill"`
+	Freeze       *int  `json:"freeze"`
+	Eliminate    *int  `json:"eliminate"`
+	FilledBlocks []int `json:"filled_blocks"`
+}
+
+func summarizeHints(usages []entity.HintUsage) HintsSpent {
+	spent := HintsSpent{FilledBlocks: []int{}}
+	add := func(total **int, price int) {
+		if *total == nil {
+			*total = new(int)
+		}
+		**total += price
+	}
+
+	for _, usage := range usages {
+		if usage.Replay {
+			continue
+		}
+		switch usage.HintType {
+		case constants.HINT_TEXTUAL:
+			add(&spent.Textual, usage.Price)
+		case constants.HINT_FREEZE:
+			add(&spent.Freeze, usage.Price)
+		case constants.HINT_ELIMINATE:
+			add(&spent.Eliminate, usage.Price)
+		case constants.HINT_FILL:
+			add(&spent.Fill, usage.Price)
+			if usage.BlockOrder != nil {
+				spent.FilledBlocks = append(spent.FilledBlocks, *usage.BlockOrder)
+			}
+		}
+	}
+	return spent
+}
+
+// TextualHintDTO is a textual hint of a level as seen by a player, the content is nil until the hint is revealed
+type TextualHintDTO struct {
+	Index   int     `json:"index"`
+	Price   int     `json:"price"`
+	Content *string `json:"content"`
+}
+
+// textualRevealed returns the positions of the textual hints revealed to the player, also after completing the level
+func textualRevealed(usages []entity.HintUsage) map[int]bool {
+	revealed := map[int]bool{}
+	for _, usage := range usages {
+		if usage.HintType == constants.HINT_TEXTUAL && usage.HintIndex != nil {
+			revealed[*usage.HintIndex] = true
+		}
+	}
+	return revealed
+}
+
+// NextTextualHint returns the position of the first textual hint that was not revealed, len(hints) if there is none
+func NextTextualHint(hints []entity.TextualHint, usages []entity.HintUsage) int {
+	revealed := textualRevealed(usages)
+	for i := range hints {
+		if !revealed[i] {
+			return i
+		}
+	}
+	return len(hints)
+}
+
+// TextualHintsOf lists the textual hints of a level in order, with the content of the ones revealed to the player
+func TextualHintsOf(hints []entity.TextualHint, usages []entity.HintUsage) []TextualHintDTO {
+	revealed := textualRevealed(usages)
+	result := []TextualHintDTO{}
+	for i, hint := range hints {
+		dto := TextualHintDTO{Index: i, Price: hint.Price}
+		if revealed[i] {
+			content := hint.Content
+			dto.Content = &content
+		}
+		result = append(result, dto)
+	}
+	return result
+}
+
+// pickDecoys chooses at random up to `count` of the decoys that were not eliminated yet
+func pickDecoys(decoys []entity.Block, eliminated []string, count int) []entity.Block {
+	remaining := utils.Filter(decoys, func(block entity.Block) bool {
+		return !slices.Contains(eliminated, block.ID)
+	})
+	rand.Shuffle(len(remaining), func(i, j int) {
+		remaining[i], remaining[j] = remaining[j], remaining[i]
+	})
+	return remaining[:min(count, len(remaining))]
+}
+
+func hintUsages(tx *gorm.DB, gameID uuid.UUID, playerID uuid.UUID) ([]entity.HintUsage, error) {
+	usages := []entity.HintUsage{}
+	result := tx.Where("game_id = ? AND player_id = ?", gameID, playerID).Order("used_at").Find(&usages)
+	return usages, result.Error
+}
+
+// PlayerGameHints returns the hints bought by a player in a level, and what they cost
+func PlayerGameHints(db *database.FinalTestinationDB, gameID uuid.UUID, playerID uuid.UUID) ([]entity.HintUsage, HintsSpent, error) {
+	usages, err := hintUsages(db.Orm, gameID, playerID)
+	if err != nil {
+		return nil, HintsSpent{}, err
+	}
+	return usages, summarizeHints(usages), nil
+}
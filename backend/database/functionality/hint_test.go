@@ -0,0 +1,72 @@
+package functionality
+
+import (
+	"backend/constants"
+	"backend/database/entity"
+	"backend/utils"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummarizeHints(t *testing.T) {
+	order := func(o int) *int { return &o }
+	price := func(p int) *int { return &p }
+
+	tests := []struct {
+		description string
+		usages      []entity.HintUsage
+		expected    HintsSpent
+	}{
+		{
+			description: "No hints",
+			usages:      []entity.HintUsage{},
+			expected:    HintsSpent{FilledBlocks: []int{}},
+		},
+		{
+			description: "A free hint is used",
+			usages:      []entity.HintUsage{{HintType: constants.HINT_TEXTUAL, Price: 0}},
+			expected:    HintsSpent{Textual: price(0), FilledBlocks: []int{}},
+		},
+		{
+			description: "Every filled block is paid",
+			usages: []entity.HintUsage{
+				{HintType: constants.HINT_FILL, Price: 20, BlockOrder: order(3)},
+				{HintType: constants.HINT_FREEZE, Price: 30},
+				{HintType: constants.HINT_FILL, Price: 20, BlockOrder: order(1)},
+			},
+			expected: HintsSpent{Fill: price(40), Freeze: price(30), FilledBlocks: []int{3, 1}},
+		},
+		{
+			description: "The hints used after completing the level are not counted",
+			usages: []entity.HintUsage{
+				{HintType: constants.HINT_TEXTUAL, Price: 10},
+				{HintType: constants.HINT_FREEZE, Price: 0, Replay: true},
+			},
+			expected: HintsSpent{Textual: price(10), FilledBlocks: []int{}},
+		},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, summarizeHints(test.usages), test.description)
+	}
+}
+
+func TestPickDecoys(t *testing.T) {
+	decoys := []entity.Block{
+		{Model: utils.Model{ID: "a"}, Content: "goodcompany"},
+		{Model: utils.Model{ID: "b"}, Content: "50%"},
+		{Model: utils.Model{ID: "c"}, Content: "25%"},
+	}
+	ids := func(blocks []entity.Block) []string {
+		return utils.Map(blocks, func(block entity.Block) string { return block.ID })
+	}
+
+	picked := pickDecoys(decoys, []string{}, 2)
+	assert.Len(t, picked, 2, "Up to count decoys are picked")
+	assert.NotEqual(t, picked[0].ID, picked[1].ID, "A decoy is picked once")
+
+	assert.Equal(t, []string{"c"}, ids(pickDecoys(decoys, []string{"a", "b"}, 2)), "Only the decoys that were not eliminated are picked")
+	assert.Empty(t, pickDecoys(decoys, []string{"a", "b", "c"}, 2), "Every decoy was already eliminated")
+	assert.Len(t, decoys, 3, "The decoys are not changed")
+}
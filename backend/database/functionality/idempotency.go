@@ -0,0 +1,49 @@
+package functionality
+
+import (
+	"backend/database/entity"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrIdempotencyKeyReused = errors.New("the idempotency key was already used for another request")
+
+// idempotencyClaim reserves `key` for a request, within the transaction that executes it. If the key belongs to a request
+// that was already executed it returns the stored response, and if that request is still running it waits for it to end.
+func idempotencyClaim(tx *gorm.DB, playerID uuid.UUID, key string, scope string, request string) (*string, error) {
+	claim := entity.IdempotencyKey{
+		PlayerID:  playerID.String(),
+		Key:       key,
+		Scope:     scope,
+		Request:   request,
+		CreatedAt: time.Now(),
+	}
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&claim)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return nil, nil
+	}
+
+	var stored entity.IdempotencyKey
+	result = tx.Where("player_id = ? AND key = ?", playerID, key).First(&stored)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if stored.Scope != scope || stored.Request != request || stored.Response == nil {
+		return nil, ErrIdempotencyKeyReused
+	}
+	return stored.Response, nil
+}
+
+// idempotencyStore saves the response of the request that claimed `key`
+func idempotencyStore(tx *gorm.DB, playerID uuid.UUID, key string, response string) error {
+	return tx.Model(&entity.IdempotencyKey{}).
+		Where("player_id = ? AND key = ?", playerID, key).
+		Update("response", response).Error
+}
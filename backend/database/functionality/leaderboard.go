@@ -0,0 +1,392 @@
+package functionality
+
+import (
+	"backend/constants"
+	"backend/database"
+	"backend/database/entity"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidLeaderboardWindow = errors.New("the window must be one of all, month and week")
+	ErrInvalidLeaderboardCursor = errors.New("invalid leaderboard cursor")
+	ErrNotInLeaderboard         = errors.New("the player is not in the leaderboard")
+)
+
+// LeaderboardOptions selects which part of a leaderboard is returned and how it is ranked
+type LeaderboardOptions struct {
+	// Only count what happened since then, nil for the all-time leaderboard
+	Since *time.Time
+	// Ranking is RANKING_COMPETITION (1, 2, 2, 4) or RANKING_DENSE (1, 2, 2, 3)
+	Ranking  string
+	PageSize int
+}
+
+type LeaderboardEntry struct {
+	Rank     int    `json:"rank"`
+	Username string `json:"username"`
+	Score    int    `json:"score"`
+}
+
+// GameLeaderboardEntry is a completion of a level, ranked by score and then by the time on the clock
+type GameLeaderboardEntry struct {
+	Rank         int       `json:"rank"`
+	Username     string    `json:"username"`
+	Score        int       `json:"score"`
+	SolveSeconds *int64    `json:"solve_seconds"`
+	EndTime      time.Time `json:"end_time"`
+}
+
+// LeaderboardPageDTO is a page of a leaderboard, `NextCursor` is nil on the last page. With a cursor, `CurrentPage`
+// is the page of the first entry.
+type LeaderboardPageDTO[T any] struct {
+	CurrentPage int     `json:"currentPage"`
+	Pages       int     `json:"pages"`
+	Entries     []T     `json:"entries"`
+	NextCursor  *string `json:"next_cursor"`
+}
+
+// RankedLeaderboardEntry is a player of the leaderboard with its rank, the players with the same score share the same rank
+type RankedLeaderboardEntry struct {
+	Rank     int    `json:"rank"`
+	Username string `json:"username"`
+	Score    int    `json:"score"`
+	Me       bool   `json:"me"`
+}
+
+// LeaderboardPositionDTO is where a player ranks, with the players right above and below it
+type LeaderboardPositionDTO struct {
+	Rank  int `json:"rank"`
+	Score int `json:"score"`
+	// Percentage of the other players that do not rank higher, 100 for the first and 0 for the last
+	Percentile float64                  `json:"percentile"`
+	Players    int                      `json:"players"`
+	Entries    []RankedLeaderboardEntry `json:"entries"`
+}
+
+// rankedRow is an entry of a leaderboard as ranked by the database. Every leaderboard is sorted by score,
+// then by seconds (the time on the clock of a level, 0 for the players leaderboard), then by whoever got there first
+// and finally by the ID of the player, so that the order is always the same.
+type rankedRow struct {
+	ID           string
+	Username     string
+	Score        int
+	Seconds      int64
+	ReachedAt    time.Time
+	SolveSeconds *int64
+	Rank         int
+	Position     int
+	PercentRank  float64
+	Players      int
+}
+
+// leaderboardCursor is the sort key of the last entry of a page, the next page starts right after it
+type leaderboardCursor struct {
+	Score     int       `json:"score"`
+	Seconds   int64     `json:"seconds"`
+	ReachedAt time.Time `json:"reached_at"`
+	ID        string    `json:"id"`
+}
+
+func encodeLeaderboardCursor(row rankedRow) string {
+	data, _ := json.Marshal(leaderboardCursor{Score: row.Score, Seconds: row.Seconds, ReachedAt: row.ReachedAt, ID: row.ID})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeLeaderboardCursor(cursor string) (*leaderboardCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidLeaderboardCursor
+	}
+	var decoded leaderboardCursor
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded.ID == "" {
+		return nil, ErrInvalidLeaderboardCursor
+	}
+	return &decoded, nil
+}
+
+// LeaderboardPages returns the number of pages of a leaderboard with `count` entries, at least 1 so that
+// an empty leaderboard has an empty first page, and the requested page clamped to them
+func LeaderboardPages(count int, pageSize int, page int) (int, int) {
+	pages := max(1, (count+pageSize-1)/pageSize)
+	return min(max(page, 1), pages), pages
+}
+
+// LeaderboardWindowStart returns when the current week or month started, nil for the all-time leaderboard
+func LeaderboardWindowStart(window string, now time.Time) (*time.Time, error) {
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	var start time.Time
+	switch window {
+	case "", constants.LEADERBOARD_WINDOW_ALL:
+		return nil, nil
+	case constants.LEADERBOARD_WINDOW_MONTH:
+		start = midnight.AddDate(0, 0, 1-now.Day())
+	case constants.LEADERBOARD_WINDOW_WEEK:
+		// time.Sunday is 0, the week starts on Monday
+		start = midnight.AddDate(0, 0, -(int(now.Weekday())+6)%7)
+	default:
+		return nil, ErrInvalidLeaderboardWindow
+	}
+	return &start, nil
+}
+
+// playersLeaderboard ranks the players by their coins. Without a window it lists the standings, the players
+// that played at least a level, otherwise it sums the coin ledger of the players with some transaction since
+// the start of the window. A player reached its score with its last transaction.
+func playersLeaderboard(tx *gorm.DB, since *time.Time) *gorm.DB {
+	if since == nil {
+		return tx.Model(&entity.PlayerStanding{}).
+			Select(`players.id, players.username, player_standings.score, 0 AS seconds,
+				player_standings.reached_at, NULL::bigint AS solve_seconds`).
+			Joins("JOIN players ON players.id = player_standings.player_id")
+	}
+	return tx.Model(&entity.Player{}).
+		Select(`players.id, players.username, SUM(coin_transactions.amount) AS score, 0 AS seconds,
+			MAX(coin_transactions.created_at) AS reached_at, NULL::bigint AS solve_seconds`).
+		Joins("JOIN coin_transactions ON coin_transactions.player_id = players.id AND coin_transactions.created_at >= ?", *since).
+		Group("players.id")
+}
+
+// gameLeaderboard lists the completions of a level, only the ones since the start of the window if there is one.
+// The levels completed before the clock existed have no time on the clock and come after the others.
+func gameLeaderboard(tx *gorm.DB, gameID uuid.UUID, since *time.Time) *gorm.DB {
+	query := tx.Model(&entity.PlayerGame{}).
+		Select(`players.id, players.username, player_games.score,
+			COALESCE(player_games.solve_seconds, 9223372036854775807) AS seconds,
+			player_games.end_time AS reached_at, player_games.solve_seconds`).
+		Joins("JOIN players ON players.id = player_games.player_id").
+		Where("player_games.game_id = ? AND player_games.end_time IS NOT NULL", gameID)
+	if since != nil {
+		query = query.Where("player_games.end_time >= ?", *since)
+	}
+	return query
+}
+
+// rankLeaderboard ranks the entries of a leaderboard with window functions: the entries with the same score
+// and seconds share the rank, while the position follows the order of the leaderboard
+func rankLeaderboard(tx *gorm.DB, board *gorm.DB, ranking string) *gorm.DB {
+	rank := "RANK()"
+	if ranking == constants.RANKING_DENSE {
+		rank = "DENSE_RANK()"
+	}
+	ranked := tx.Table("(?) AS board", board).
+		Select(`board.*,
+			` + rank + ` OVER (ORDER BY score DESC, seconds ASC) AS rank,
+			ROW_NUMBER() OVER (ORDER BY score DESC, seconds ASC, reached_at ASC, id ASC) AS position,
+			PERCENT_RANK() OVER (ORDER BY score DESC, seconds ASC) AS percent_rank,
+			COUNT(*) OVER () AS players`)
+	return tx.Table("(?) AS ranked", ranked)
+}
+
+// leaderboardPage returns the entries of a page, starting from 1, or the ones after the cursor if there is one
+func leaderboardPage(tx *gorm.DB, board *gorm.DB, page int, cursor string, options LeaderboardOptions) ([]rankedRow, *string, error) {
+	query := rankLeaderboard(tx, board, options.Ranking)
+	if cursor != "" {
+		after, err := decodeLeaderboardCursor(cursor)
+		if err != nil {
+			return nil, nil, err
+		}
+		query = query.Where(`score < ?
+			OR (score = ? AND seconds > ?)
+			OR (score = ? AND seconds = ? AND reached_at > ?)
+			OR (score = ? AND seconds = ? AND reached_at = ? AND id > ?)`,
+			after.Score,
+			after.Score, after.Seconds,
+			after.Score, after.Seconds, after.ReachedAt,
+			after.Score, after.Seconds, after.ReachedAt, after.ID)
+	} else {
+		query = query.Where("position > ?", (page-1)*options.PageSize)
+	}
+
+	// one more entry tells whether there is a next page
+	rows := []rankedRow{}
+	if err := query.Order("position").Limit(options.PageSize + 1).Scan(&rows).Error; err != nil {
+		return nil, nil, err
+	}
+	if len(rows) <= options.PageSize {
+		return rows, nil, nil
+	}
+	rows = rows[:options.PageSize]
+	next := encodeLeaderboardCursor(rows[len(rows)-1])
+	return rows, &next, nil
+}
+
+// cursorPage returns the page of the first of the rows that follow a cursor, the last page when there are none
+func cursorPage(rows []rankedRow, pageSize int, pages int) int {
+	if len(rows) == 0 {
+		return pages
+	}
+	return (rows[0].Position-1)/pageSize + 1
+}
+
+func leaderboardCount(tx *gorm.DB, board *gorm.DB) (int, error) {
+	var count int64
+	result := tx.Table("(?) AS board", board).Count(&count)
+	return int(count), result.Error
+}
+
+// GetLeaderboardPlayers ranks the players by the balance of their coin ledger
+func GetLeaderboardPlayers(database *database.FinalTestinationDB, page int, cursor string, options LeaderboardOptions) (*LeaderboardPageDTO[LeaderboardEntry], error) {
+	board := playersLeaderboard(database.Orm, options.Since)
+	count, err := leaderboardCount(database.Orm, board)
+	if err != nil {
+		return nil, err
+	}
+
+	result := LeaderboardPageDTO[LeaderboardEntry]{}
+	result.CurrentPage, result.Pages = LeaderboardPages(count, options.PageSize, page)
+	rows, next, err := leaderboardPage(database.Orm, board, result.CurrentPage, cursor, options)
+	if err != nil {
+		return nil, err
+	}
+	result.NextCursor = next
+	// the cursor does not follow the pages, so the page is the one of the first entry
+	if cursor != "" {
+		result.CurrentPage = cursorPage(rows, options.PageSize, result.Pages)
+	}
+	result.Entries = make([]LeaderboardEntry, 0, len(rows))
+	for _, row := range rows {
+		result.Entries = append(result.Entries, LeaderboardEntry{Rank: row.Rank, Username: row.Username, Score: row.Score})
+	}
+	return &result, nil
+}
+
+// GetGameLeaderboard ranks the players that completed a level by score, then by the time on the clock and
+// then by who completed it first
+func GetGameLeaderboard(database *database.FinalTestinationDB, gameID uuid.UUID, page int, cursor string, options LeaderboardOptions) (*LeaderboardPageDTO[GameLeaderboardEntry], error) {
+	board := gameLeaderboard(database.Orm, gameID, options.Since)
+	count, err := leaderboardCount(database.Orm, board)
+	if err != nil {
+		return nil, err
+	}
+
+	result := LeaderboardPageDTO[GameLeaderboardEntry]{}
+	result.CurrentPage, result.Pages = LeaderboardPages(count, options.PageSize, page)
+	rows, next, err := leaderboardPage(database.Orm, board, result.CurrentPage, cursor, options)
+	if err != nil {
+		return nil, err
+	}
+	result.NextCursor = next
+	// the cursor does not follow the pages, so the page is the one of the first entry
+	if cursor != "" {
+		result.CurrentPage = cursorPage(rows, options.PageSize, result.Pages)
+	}
+	result.Entries = make([]GameLeaderboardEntry, 0, len(rows))
+	for _, row := range rows {
+		result.Entries = append(result.Entries, GameLeaderboardEntry{
+			Rank:         row.Rank,
+			Username:     row.Username,
+			Score:        row.Score,
+			SolveSeconds: row.SolveSeconds,
+			EndTime:      row.ReachedAt,
+		})
+	}
+	return &result, nil
+}
+
+// GetLeaderboardPosition ranks the player among the others, and returns the `around` players right above and below it
+func GetLeaderboardPosition(database *database.FinalTestinationDB, playerID uuid.UUID, around int, options LeaderboardOptions) (*LeaderboardPositionDTO, error) {
+	ranked := rankLeaderboard(database.Orm, playersLeaderboard(database.Orm, options.Since), options.Ranking)
+
+	rows := []rankedRow{}
+	result := database.Orm.Raw(`WITH ranked AS (?), me AS (
+			SELECT position FROM ranked WHERE id = ?
+		)
+		SELECT ranked.* FROM ranked, me
+		WHERE ranked.position BETWEEN me.position - ? AND me.position + ?
+		ORDER BY ranked.position`, ranked.Select("*"), playerID, around, around).
+		Scan(&rows)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	position := LeaderboardPositionDTO{Entries: []RankedLeaderboardEntry{}}
+	found := false
+	for _, row := range rows {
+		me := row.ID == playerID.String()
+		if me {
+			found = true
+			position.Rank = row.Rank
+			position.Score = row.Score
+			position.Percentile = math.Round((1-row.PercentRank)*10000) / 100
+			position.Players = row.Players
+		}
+		position.Entries = append(position.Entries, RankedLeaderboardEntry{Rank: row.Rank, Username: row.Username, Score: row.Score, Me: me})
+	}
+	if !found {
+		return nil, ErrNotInLeaderboard
+	}
+	return &position, nil
+}
+
+// RankChange is the new all-time rank of a player
+type RankChange struct {
+	Username     string `json:"username"`
+	Rank         int    `json:"rank"`
+	PreviousRank int    `json:"previous_rank"`
+	Score        int    `json:"score"`
+}
+
+// LeaderboardRankChanges returns how the all-time ranks changed when a player gained `gained` coins: the rank of
+// the player first, then the ones of the players it overtook, which moved down by one. Nothing is returned when
+// no rank changed. The all-time leaderboard ranks only by score, like RANKING_COMPETITION, so a rank is one more
+// than the number of higher scores and the standings do not need to be ranked as a whole.
+func LeaderboardRankChanges(database *database.FinalTestinationDB, playerID uuid.UUID, gained int) ([]RankChange, error) {
+	changes := []RankChange{}
+	if gained <= 0 {
+		return changes, nil
+	}
+	var standing entity.PlayerStanding
+	if err := database.Orm.First(&standing, "player_id = ?", playerID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return changes, nil
+		}
+		return nil, err
+	}
+	previous := standing.Score - gained
+
+	// the players the player overtook, or tied with, and the player itself
+	rows := []struct {
+		PlayerID string
+		RankChange
+	}{}
+	result := database.Orm.Table("player_standings AS standing").
+		Select(`standing.player_id, players.username, standing.score,
+			1 + (SELECT COUNT(*) FROM player_standings AS higher WHERE higher.score > standing.score) AS rank`).
+		Joins("JOIN players ON players.id = standing.player_id").
+		Where("standing.score >= ? AND standing.score <= ?", previous, standing.Score).
+		Order("standing.score DESC, standing.player_id").
+		Scan(&rows)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	var player *RankChange
+	overtaken := []RankChange{}
+	passed := 0
+	for i, row := range rows {
+		switch {
+		case row.PlayerID == playerID.String():
+			player = &rows[i].RankChange
+		case row.Score < standing.Score:
+			row.PreviousRank = row.Rank - 1
+			overtaken = append(overtaken, row.RankChange)
+		}
+		// the players that were above the player, and no longer are
+		if row.PlayerID != playerID.String() && row.Score > previous {
+			passed++
+		}
+	}
+	if player == nil || (passed == 0 && len(overtaken) == 0) {
+		return changes, nil
+	}
+	player.PreviousRank = player.Rank + passed
+	return append(append(changes, *player), overtaken...), nil
+}
@@ -0,0 +1,108 @@
+package functionality
+
+import (
+	"backend/constants"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLeaderboardWindowStart(t *testing.T) {
+	// A Wednesday
+	now := time.Date(2024, time.May, 15, 16, 30, 0, 0, time.UTC)
+	may := func(day int) *time.Time {
+		start := time.Date(2024, time.May, day, 0, 0, 0, 0, time.UTC)
+		return &start
+	}
+
+	tests := []struct {
+		description string
+		window      string
+		now         time.Time
+		expected    *time.Time
+		valid       bool
+	}{
+		{description: "The all-time leaderboard", window: constants.LEADERBOARD_WINDOW_ALL, now: now, valid: true},
+		{description: "No window", window: "", now: now, valid: true},
+		{
+			description: "The week starts on Monday",
+			window:      constants.LEADERBOARD_WINDOW_WEEK,
+			now:         now,
+			expected:    may(13),
+			valid:       true,
+		},
+		{
+			description: "On Sunday the week started six days before",
+			window:      constants.LEADERBOARD_WINDOW_WEEK,
+			now:         time.Date(2024, time.May, 19, 10, 0, 0, 0, time.UTC),
+			expected:    may(13),
+			valid:       true,
+		},
+		{
+			description: "The month starts on the first day",
+			window:      constants.LEADERBOARD_WINDOW_MONTH,
+			now:         now,
+			expected:    may(1),
+			valid:       true,
+		},
+		{description: "An unknown window", window: "year", now: now},
+	}
+
+	for _, test := range tests {
+		start, err := LeaderboardWindowStart(test.window, test.now)
+		if test.valid {
+			assert.NoError(t, err, test.description)
+			assert.Equal(t, test.expected, start, test.description)
+		} else {
+			assert.ErrorIs(t, err, ErrInvalidLeaderboardWindow, test.description)
+		}
+	}
+}
+
+func TestLeaderboardPages(t *testing.T) {
+	tests := []struct {
+		description  string
+		count        int
+		page         int
+		expectedPage int
+		pages        int
+	}{
+		{description: "An empty leaderboard has an empty first page", count: 0, page: 1, expectedPage: 1, pages: 1},
+		{description: "A full last page", count: 50, page: 2, expectedPage: 2, pages: 2},
+		{description: "A partial last page", count: 51, page: 3, expectedPage: 3, pages: 3},
+		{description: "A page past the end is the last page", count: 30, page: 5, expectedPage: 2, pages: 2},
+		{description: "A page before the first is the first page", count: 30, page: 0, expectedPage: 1, pages: 2},
+	}
+
+	for _, test := range tests {
+		page, pages := LeaderboardPages(test.count, 25, test.page)
+		assert.Equal(t, test.expectedPage, page, test.description)
+		assert.Equal(t, test.pages, pages, test.description)
+	}
+}
+
+func TestLeaderboardCursor(t *testing.T) {
+	row := rankedRow{
+		ID:        "c7c5a6c4-2d0e-4c5e-9d4e-0d7c1f0b6a11",
+		Score:     120,
+		Seconds:   45,
+		ReachedAt: time.Date(2024, time.May, 15, 16, 30, 0, 123456000, time.UTC),
+	}
+
+	cursor, err := decodeLeaderboardCursor(encodeLeaderboardCursor(row))
+	assert.NoError(t, err)
+	assert.Equal(t, leaderboardCursor{Score: 120, Seconds: 45, ReachedAt: row.ReachedAt, ID: row.ID}, *cursor, "The cursor keeps the sort key of the entry")
+
+	for _, invalid := range []string{"not base64!", "bm90IGpzb24", "e30"} {
+		_, err = decodeLeaderboardCursor(invalid)
+		assert.ErrorIs(t, err, ErrInvalidLeaderboardCursor, invalid)
+	}
+}
+
+func TestCursorPage(t *testing.T) {
+	assert.Equal(t, 2, cursorPage([]rankedRow{{Position: 26}, {Position: 27}}, 25, 4), "The first entry of the second page")
+	assert.Equal(t, 2, cursorPage([]rankedRow{{Position: 30}}, 25, 4), "An entry in the middle of the second page")
+	assert.Equal(t, 1, cursorPage([]rankedRow{{Position: 1}}, 25, 4))
+	assert.Equal(t, 4, cursorPage([]rankedRow{}, 25, 4), "No entries after the cursor")
+}
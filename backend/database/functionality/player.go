@@ -1,6 +1,7 @@
 package functionality
 
 import (
+	"backend/constants"
 	"backend/database"
 	"backend/database/entity"
 	"backend/utils"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type AvailableLevelDTO struct {
@@ -20,20 +22,25 @@ type AvailableLevelDTO struct {
 	Description   string `json:"description"`
 }
 
+// LevelProgress is a level played by the player, the points used are nil for the hints that were not bought
 type LevelProgress struct {
-	Title                  string     `json:"title"`
-	Score                  int        `json:"score"`
-	StartTime              time.Time  `json:"start_time"`
-	EndTime                *time.Time `json:"end_time"`
-	TextualHintPointsUsed  int        `json:"textual_hint_points_used"`
-	HintSolutionPointsUsed int        `json:"hint_solution_points_used"`
-	TimeFreezePointsUsed   int        `json:"time_freeze_points_used"`
+	GameID                 string             `json:"-"`
+	Title                  string             `json:"title"`
+	Score                  int                `json:"score"`
+	StartTime              time.Time          `json:"start_time"`
+	EndTime                *time.Time         `json:"end_time"`
+	TextualHintPointsUsed  *int               `json:"textual_hint_points_used" gorm:"-"`
+	HintSolutionPointsUsed *int               `json:"hint_solution_points_used" gorm:"-"`
+	TimeFreezePointsUsed   *int               `json:"time_freeze_points_used" gorm:"-"`
+	EliminatePointsUsed    *int               `json:"eliminate_points_used" gorm:"-"`
+	Hints                  []entity.HintUsage `json:"hints" gorm:"-"`
 }
 type ProfileDTO struct {
-	ProfileImage string          `json:"profileImage"`
-	Username     string          `json:"username"`
-	Email        string          `json:"email"`
-	Levels       []LevelProgress `json:"levels"`
+	ProfileImage  string          `json:"profileImage"`
+	Username      string          `json:"username"`
+	Email         string          `json:"email"`
+	EmailVerified bool            `json:"emailVerified"`
+	Levels        []LevelProgress `json:"levels"`
 }
 
 type IconDTO struct {
@@ -56,6 +63,7 @@ func PlayerCreate(database *database.FinalTestinationDB, username string, passwo
 		IconID:   "1",
 		Secure:   false,
 		SameSite: "None",
+		Role:     constants.ROLE_PLAYER,
 	}
 
 	result := database.Orm.Create(&player)
@@ -180,7 +188,7 @@ func Profile(database *database.FinalTestinationDB, player *entity.Player) (*Pro
 	res := database.Orm.Table("\"player_games\" AS pg").
 		Joins("JOIN games ON pg.game_id = games.id ").
 		Where("pg.player_id = ?", player.ID).
-		Select("title,score, start_time,end_time,textual_hint_points_used,hint_solution_points_used,time_freeze_points_used").
+		Select("pg.game_id, title, score, start_time, end_time").
 		Order("pg.end_time DESC").
 		Scan(&levelProgress)
 
@@ -188,6 +196,22 @@ func Profile(database *database.FinalTestinationDB, player *entity.Player) (*Pro
 		return nil, res.Error
 	}
 
+	var usages []entity.HintUsage
+	res = database.Orm.Where("player_id = ?", player.ID).Order("used_at").Find(&usages)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	for i := range levelProgress {
+		level := &levelProgress[i]
+		level.Hints = utils.Filter(usages, func(usage entity.HintUsage) bool { return usage.GameID == level.GameID })
+		spent := summarizeHints(level.Hints)
+		level.TextualHintPointsUsed = spent.Textual
+		level.HintSolutionPointsUsed = spent.Fill
+		level.TimeFreezePointsUsed = spent.Freeze
+		level.EliminatePointsUsed = spent.Eliminate
+	}
+
 	var propic string
 	res = database.Orm.Table("icons").
 		Joins("JOIN players ON icons.id = players.icon_id ").
@@ -202,6 +226,7 @@ func Profile(database *database.FinalTestinationDB, player *entity.Player) (*Pro
 	var profile ProfileDTO
 	profile.Levels = levelProgress
 	profile.Email = player.Email
+	profile.EmailVerified = player.EmailVerified
 	profile.Username = player.Username
 	profile.ProfileImage = propic
 
@@ -229,3 +254,34 @@ func ChangeIcon(database *database.FinalTestinationDB, player string, icon strin
 		Update("icon_id", icon)
 	return res.Error
 }
+
+var ErrLastAdmin = errors.New("the last admin cannot lose its role")
+
+// PlayerSetRole changes the role of a player, refusing to demote the last admin so that someone can still manage the roles.
+// The admins are locked, so that two admins demoting each other at the same time cannot both succeed.
+func PlayerSetRole(database *database.FinalTestinationDB, playerID uuid.UUID, role string) error {
+	return database.Orm.Transaction(func(tx *gorm.DB) error {
+		var admins []string
+		err := tx.Model(&entity.Player{}).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("role = ?", constants.ROLE_ADMIN).
+			Pluck("id", &admins).Error
+		if err != nil {
+			return err
+		}
+		if role != constants.ROLE_ADMIN && len(admins) == 1 && admins[0] == playerID.String() {
+			return ErrLastAdmin
+		}
+
+		res := tx.Model(&entity.Player{}).
+			Where("id = ?", playerID).
+			Update("role", role)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
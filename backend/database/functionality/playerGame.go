@@ -4,95 +4,77 @@ import (
 	"backend/constants"
 	"backend/database"
 	"backend/database/entity"
+	"backend/utils"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
-	"math"
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-type LeaderboardEntry struct {
-	Username string `json:"username"`
-	Score    int    `json:"score"`
-}
-
 type GameHintsAvailability struct {
 	FreezeTimeUsed   int `json:"freeze_time_spent_coins"`
 	TextualHintUsed  int `json:"textual_hint_spent_coins"`
 	SolutionHintUsed int `json:"solution_hint_spent_coins"`
 }
 
-func PlayerGetTotalCoins(db *database.FinalTestinationDB, playerID uuid.UUID) (int, error) {
-	var totalCoins int
-
-	// If the player hasn't completed any game, then the `Scan` call will not find any tuple, and the `totalCoins` will be 0 (default value)
-	err := db.Orm.Model(&entity.PlayerGame{}).
-		Select("SUM(score - textual_hint_points_used - hint_solution_points_used - time_freeze_points_used) as coins").
-		Group("player_id").
-		Having("player_id = ?", playerID).
-		Scan(&totalCoins)
-
-	return totalCoins, err.Error
-}
-
-func PlayerGameCreateMaxScore(db *database.FinalTestinationDB, gameID uuid.UUID, playerID uuid.UUID, end_time int64) (int, float64, error) {
-
-	game_completion_data := struct {
-		StartTime            int64  `json:"start_time"`
-		PerfectTimeslot      int64  `json:"perfect_timeslot"`
-		GreatTimeslot        int64  `json:"great_timeslot"`
-		MediumTimeslot       int64  `json:"medium_timeslot"`
-		NotSoGoodTimeslot    int64  `json:"not_so_good_timeslot"`
-		MaxScore             int64  `json:"max_score"`
-		WrongAttemptCost     int64  `json:"wrong_attempt_cost"`
-		Attempts             int64  `json:"attempts"`
-		TimeFreezeDuration   int64  `json:"time_freeze_duration"`
-		TimeFreezePointsUsed *int64 `json:"time_freeze_points_used"`
-	}{}
-
-	tx := db.Orm.Model(&entity.PlayerGame{}).
-		Joins("JOIN games ON player_games.game_id = games.id").
-		Where("games.id = ? AND player_games.player_id = ?", gameID, playerID).
-		Select("EXTRACT(EPOCH FROM player_games.start_time)::bigint as start_time, perfect_timeslot, great_timeslot, medium_timeslot, not_so_good_timeslot, max_score, wrong_attempt_cost, attempts, time_freeze_duration, time_freeze_points_used").
-		First(&game_completion_data)
-	log.Println("game_completion_data: ", game_completion_data)
-
+// PlayerGameCreateMaxScore stops the clock of a level that was just completed, stores its score and
+// adds it to the coins of the player. A level that is already completed keeps its score,
+// and only the call that completed the level reports it as completed.
+func PlayerGameCreateMaxScore(db *database.FinalTestinationDB, gameID uuid.UUID, playerID uuid.UUID, end_time int64) (int, float64, bool, error) {
+	var game entity.Game
+	tx := db.Orm.Select("max_score, wrong_attempt_cost, perfect_timeslot, great_timeslot, medium_timeslot, not_so_good_timeslot, scoring").
+		Where("id = ?", gameID).
+		First(&game)
 	if tx.Error != nil {
-		return 0, 0, tx.Error
+		return 0, 0, false, tx.Error
 	}
 
-	time_used := end_time - game_completion_data.StartTime
-	if game_completion_data.TimeFreezePointsUsed != nil {
-		time_used -= game_completion_data.TimeFreezeDuration
-	}
-	var multiplier float64
-	if time_used < game_completion_data.PerfectTimeslot {
-		multiplier = 1
-	} else if time_used < game_completion_data.GreatTimeslot {
-		multiplier = 0.8
-	} else if time_used < game_completion_data.MediumTimeslot {
-		multiplier = 0.6
-	} else if time_used < game_completion_data.NotSoGoodTimeslot {
-		multiplier = 0.4
-	} else {
-		multiplier = 0.2
-	}
-	log.Println("time_used: ", time_used)
-	log.Println("multiplier: ", multiplier)
-
 	end_time_time := time.Unix(end_time, 0)
 
-	score := int(math.Max(float64(game_completion_data.MaxScore)*multiplier-float64(game_completion_data.Attempts)*float64(game_completion_data.WrongAttemptCost), float64(game_completion_data.MaxScore)*0.2))
+	var score int
+	var multiplier float64
+	completed := false
+	err := db.Orm.Transaction(func(tx *gorm.DB) error {
+		playerGame, err := clockLock(tx, gameID, playerID)
+		if err != nil {
+			return err
+		}
+		if playerGame.EndTime != nil {
+			score = playerGame.Score
+			return nil
+		}
 
-	log.Println("score: ", score)
+		elapsed, err := clockStop(tx, gameID, playerID, playerGame.StartTime, end_time_time)
+		if err != nil {
+			return err
+		}
+		time_used := int64(elapsed.Seconds())
+
+		score, multiplier = game.Scoring.Score(GameScoringLevel(&game), time_used, playerGame.Attempts)
+		log.Println("time_used: ", time_used)
+		log.Println("multiplier: ", multiplier)
+		log.Println("score: ", score)
+
+		// update end time and score on the db
+		err = tx.Model(&entity.PlayerGame{}).
+			Where("game_id = ? AND player_id = ?", gameID, playerID).
+			Updates(entity.PlayerGame{Score: score, EndTime: &end_time_time, SolveSeconds: &time_used}).Error
+		if err != nil {
+			return err
+		}
 
-	// update end time and score on the db
-	tz := db.Orm.Model(&entity.PlayerGame{}).Where("game_id = ? AND player_id = ?", gameID, playerID).Updates(entity.PlayerGame{Score: score, EndTime: &end_time_time})
+		_, err = coinRecord(tx, playerID, constants.COIN_EARN, score, constants.COIN_REASON_LEVEL_COMPLETED, &gameID, nil)
+		completed = true
+		return err
+	})
 
-	return score, multiplier, tz.Error
+	return score, multiplier, completed && err == nil, err
 }
 
 func PlayerIncrementAttempts(db *database.FinalTestinationDB, gameID uuid.UUID, playerID uuid.UUID) error {
@@ -108,46 +90,101 @@ func PlayerGameGet(database *database.FinalTestinationDB, playerID uuid.UUID, ga
 	return &playerGame, result.Error
 }
 
-func GetLeaderboardPlayers(database *database.FinalTestinationDB, page int) (*[]LeaderboardEntry, error) {
-	var playersScore []LeaderboardEntry
-
-	//takes score as it is created in PlayerGameCreateMaxScore and subtracts hints used
-	result := database.Orm.Model(&entity.PlayerGame{}).
-		Joins("JOIN players ON player_games.player_id = players.id").
-		Select("players.username, SUM(player_games.score - player_games.textual_hint_points_used - player_games.hint_solution_points_used - player_games.time_freeze_points_used) AS score").
-		Group("players.id").
-		Order("score DESC").
-		Limit(constants.PAGE_SIZE).
-		Offset((page * constants.PAGE_SIZE) - 1).
-		Scan(&playersScore)
+func CheckGamePlayerCompleted(database *database.FinalTestinationDB, gameID uuid.UUID, playerID uuid.UUID) (bool, error) {
+	var count int64
+	result := database.Orm.Model(&entity.PlayerGame{}).Where("game_id = ? AND player_id = ? AND end_time IS NOT NULL", gameID, playerID).Count(&count)
+	if result.Error != nil {
+		return false, result.Error
+	}
 
-	return &playersScore, result.Error
+	return count > 0, nil
 }
 
-func GetLeaderbordElementsNumber(database *database.FinalTestinationDB) (int, error) {
-	var elemsNumber int
+// LevelCompletion is a level completed by a player, as announced by the live events
+type LevelCompletion struct {
+	GameID       string `json:"game_id"`
+	GameTitle    string `json:"game_title"`
+	Username     string `json:"username"`
+	Score        int    `json:"score"`
+	SolveSeconds *int64 `json:"solve_seconds"`
+	// How many players completed the level before, and including, this one: 1 for the first solve
+	Position int `json:"position"`
+}
 
-	result := database.Orm.Model(&entity.PlayerGame{}).Select("COUNT(DISTINCT player_id)").Scan(&elemsNumber)
+// LevelCompletionGet returns the completion of a level by a player. The players that completed it
+// in the same second are ordered by ID, so that a level has a single first solve.
+func LevelCompletionGet(database *database.FinalTestinationDB, gameID uuid.UUID, playerID uuid.UUID) (*LevelCompletion, error) {
+	var completion LevelCompletion
+	result := database.Orm.Table("player_games AS pg").
+		Select(`pg.game_id, games.title AS game_title, players.username, pg.score, pg.solve_seconds,
+			(SELECT COUNT(*) FROM player_games AS other
+				WHERE other.game_id = pg.game_id AND other.end_time IS NOT NULL
+					AND (other.end_time < pg.end_time OR (other.end_time = pg.end_time AND other.player_id <= pg.player_id))
+			) AS position`).
+		Joins("JOIN games ON games.id = pg.game_id").
+		Joins("JOIN players ON players.id = pg.player_id").
+		Where("pg.game_id = ? AND pg.player_id = ? AND pg.end_time IS NOT NULL", gameID, playerID).
+		Take(&completion)
 	if result.Error != nil {
-		return -1, result.Error
+		return nil, result.Error
 	}
-
-	return elemsNumber, nil
+	return &completion, nil
 }
 
-func CheckGamePlayerCompleted(database *database.FinalTestinationDB, gameID uuid.UUID, playerID uuid.UUID) (bool, error) {
-	var count int64
-	result := database.Orm.Model(&entity.PlayerGame{}).Where("game_id = ? AND player_id = ? AND end_time IS NOT NULL", gameID, playerID).Count(&count)
-	if result.Error != nil {
-		return false, result.Error
-	}
+// PlayerGameUseHint buys a hint in a single transaction, so that concurrent purchases cannot spend the same coins
+// or buy the same hint twice. A purchase retried with the same `idempotencyKey` returns the hint without buying it again.
+func PlayerGameUseHint(db *database.FinalTestinationDB, gameID uuid.UUID, playerID uuid.UUID, hintType string, order *int, idempotencyKey string) (int, *string, error) {
+	status := 200
+	var hintContent *string
+
+	err := db.Orm.Transaction(func(tx *gorm.DB) error {
+		if idempotencyKey != "" {
+			request := hintType
+			if order != nil {
+				request = fmt.Sprintf("%s:%d", hintType, *order)
+			}
+			stored, err := idempotencyClaim(tx, playerID, idempotencyKey, "hint:"+gameID.String(), request)
+			if err != nil {
+				status = 500
+				if errors.Is(err, ErrIdempotencyKeyReused) {
+					status = 422
+				}
+				return err
+			}
+			if stored != nil {
+				hintContent = stored
+				return nil
+			}
+		}
 
-	return count > 0, nil
+		var err error
+		status, hintContent, err = hintPurchase(tx, gameID, playerID, hintType, order)
+		if err != nil {
+			return err
+		}
+
+		if idempotencyKey != "" {
+			if err := idempotencyStore(tx, playerID, idempotencyKey, *hintContent); err != nil {
+				status = 500
+				return err
+			}
+		}
+		return nil
+	})
+
+	return status, hintContent, err
 }
 
-func PlayerGameUseHint(db *database.FinalTestinationDB, gameID uuid.UUID, playerID uuid.UUID, hintType string, order *int, playerCoins int) (int, *string, error) {
+func hintPurchase(tx *gorm.DB, gameID uuid.UUID, playerID uuid.UUID, hintType string, order *int) (int, *string, error) {
+	// The player is locked first, so that purchases on different levels cannot spend the same coins.
+	// NO KEY UPDATE still lets other transactions insert rows that reference the player, like the coins earned by a level.
+	err := tx.Clauses(clause.Locking{Strength: "NO KEY UPDATE"}).Select("id").First(&entity.Player{}, "id = ?", playerID)
+	if err.Error != nil {
+		return 500, nil, err.Error
+	}
+
 	var pg entity.PlayerGame
-	err := db.Orm.First(&pg, "game_id = ? AND player_id = ?", gameID, playerID)
+	err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&pg, "game_id = ? AND player_id = ?", gameID, playerID)
 	if err.Error != nil {
 		if err.Error == gorm.ErrRecordNotFound {
 			return 404, nil, err.Error
@@ -155,42 +192,54 @@ func PlayerGameUseHint(db *database.FinalTestinationDB, gameID uuid.UUID, player
 		return 400, nil, err.Error
 	}
 
-	hintUsed := pg.TextualHintPointsUsed
-	if hintType == "freeze" {
-		hintUsed = pg.TimeFreezePointsUsed
-	} else if hintType == "fill" {
-		hintUsed = pg.HintSolutionPointsUsed
-	}
+	// Once the level is completed the hints are free, and can be used again
+	replay := pg.EndTime != nil
 
-	if hintUsed > 0 && pg.EndTime == nil {
-		// Forbidden
-		return 403, nil, fmt.Errorf("cannot buy hint with type %s a second time", hintType)
+	usages, usagesErr := hintUsages(tx, gameID, playerID)
+	if usagesErr != nil {
+		return 500, nil, fmt.Errorf("couldn't get the hints already bought")
+	}
+	if !replay {
+		for _, usage := range usages {
+			if usage.HintType != hintType || usage.Replay {
+				continue
+			}
+			if hintType == constants.HINT_FREEZE {
+				// Forbidden
+				return 403, nil, fmt.Errorf("cannot buy hint with type %s a second time", hintType)
+			}
+			if hintType == constants.HINT_FILL && usage.BlockOrder != nil && order != nil && *usage.BlockOrder == *order {
+				return 403, nil, fmt.Errorf("cannot fill block %d a second time", *order)
+			}
+		}
 	}
 
 	var game entity.Game
-	err = db.Orm.Select("time_freeze_price, hint_solution_price, textual_hint_price, textual_hint, time_freeze_duration").First(&game, "id = ?", gameID)
+	err = tx.Select("time_freeze_price, hint_solution_price, textual_hints, time_freeze_duration, eliminate_decoys_count, eliminate_decoys_price").First(&game, "id = ?", gameID)
 	if err.Error != nil {
-		return 500, nil, nil
+		return 500, nil, err.Error
 	}
 
 	var hintContent string
-	if hintType == "textual" {
-		if pg.EndTime == nil && playerCoins < game.TextualHintPrice {
-			return 400, nil, fmt.Errorf("not enough coins")
-		}
-		hintContent = game.TextualHint
-		pg.TextualHintPointsUsed = game.TextualHintPrice
-	} else if hintType == "freeze" {
-		if pg.EndTime == nil && playerCoins < game.TimeFreezePrice {
-			return 400, nil, fmt.Errorf("not enough coins")
+	var price int
+	var blockOrder *int
+	var hintIndex *int
+	var eliminated []string
+	switch hintType {
+	case constants.HINT_TEXTUAL:
+		// The textual hints are revealed one at a time, in order
+		next := NextTextualHint(game.TextualHints, usages)
+		if next == len(game.TextualHints) {
+			return 403, nil, fmt.Errorf("every textual hint was already revealed")
 		}
+		hintContent = game.TextualHints[next].Content
+		price = game.TextualHints[next].Price
+		hintIndex = &next
+	case constants.HINT_FREEZE:
 		hintContent = fmt.Sprint(game.TimeFreezeDuration)
-		pg.TimeFreezePointsUsed = game.TimeFreezePrice
-	} else if hintType == "fill" {
-		if pg.EndTime == nil && playerCoins < game.HintSolutionPrice {
-			return 400, nil, fmt.Errorf("not enough coins")
-		}
-		res_blocks := db.Orm.Model(&entity.Block{}).Select("content").Where("game_id = ? AND \"order\" = ? AND skeleton = false", gameID, order).First(&hintContent)
+		price = game.TimeFreezePrice
+	case constants.HINT_FILL:
+		res_blocks := tx.Model(&entity.Block{}).Select("content").Where("game_id = ? AND \"order\" = ? AND skeleton = false", gameID, order).First(&hintContent)
 		if res_blocks.Error != nil {
 			if errors.Is(res_blocks.Error, gorm.ErrRecordNotFound) {
 				return 400, nil, fmt.Errorf("block not found")
@@ -199,20 +248,76 @@ func PlayerGameUseHint(db *database.FinalTestinationDB, gameID uuid.UUID, player
 			return 500, nil, fmt.Errorf("something went wrong, try again")
 
 		}
-		pg.HintSolutionPointsUsed = game.HintSolutionPrice
+		price = game.HintSolutionPrice
+		blockOrder = order
+	case constants.HINT_ELIMINATE:
+		if game.EliminateDecoysCount == 0 {
+			return 403, nil, fmt.Errorf("this level has no decoy blocks to eliminate")
+		}
+		var decoys []entity.Block
+		if err := tx.Select("id, content").Where("game_id = ? AND \"order\" IS NULL", gameID).Find(&decoys).Error; err != nil {
+			return 500, nil, fmt.Errorf("something went wrong, try again")
+		}
+		picked := pickDecoys(decoys, pg.EliminatedBlocks, game.EliminateDecoysCount)
+		if len(picked) == 0 {
+			return 403, nil, fmt.Errorf("every decoy block was already eliminated")
+		}
+		// The content of the hint is the list of the blocks removed from the ones of the player
+		contents, _ := json.Marshal(utils.Map(picked, func(block entity.Block) string { return block.Content }))
+		hintContent = string(contents)
+		eliminated = append(slices.Clone(pg.EliminatedBlocks), utils.Map(picked, func(block entity.Block) string { return block.ID })...)
+		price = game.EliminateDecoysPrice
 	}
 
-	// Do not spend points if the game is already completed
-	if pg.EndTime != nil {
-		return 200, &hintContent, nil
+	if replay {
+		price = 0
 	}
 
-	//spend coins
-	res := db.Orm.Where("game_id = ? AND player_id = ?", gameID, playerID).Updates(&pg)
+	if price > 0 {
+		playerCoins, coinsErr := coinBalance(tx, playerID)
+		if coinsErr != nil {
+			return 500, nil, fmt.Errorf("couldn't get the number of coins of the player")
+		}
+		if playerCoins < price {
+			return 400, nil, fmt.Errorf("not enough coins")
+		}
+	}
 
+	res := tx.Create(&entity.HintUsage{
+		Model:      utils.Model{ID: uuid.New().String()},
+		PlayerID:   playerID.String(),
+		GameID:     gameID.String(),
+		HintType:   hintType,
+		Price:      price,
+		BlockOrder: blockOrder,
+		HintIndex:  hintIndex,
+		Replay:     replay,
+		UsedAt:     time.Now(),
+	})
 	if res.Error != nil {
 		return 500, nil, fmt.Errorf("couldn't update the hint usage")
 	}
 
+	//spend coins
+	if price > 0 {
+		if _, err := coinRecord(tx, playerID, constants.COIN_SPEND, price, constants.COIN_REASON_HINT_PREFIX+hintType, &gameID, nil); err != nil {
+			return 500, nil, fmt.Errorf("couldn't spend the coins")
+		}
+	}
+
+	if eliminated != nil {
+		res := tx.Model(&entity.PlayerGame{}).Where("game_id = ? AND player_id = ?", gameID, playerID).
+			Select("eliminated_blocks").Updates(entity.PlayerGame{EliminatedBlocks: eliminated})
+		if res.Error != nil {
+			return 500, nil, fmt.Errorf("couldn't eliminate the decoy blocks")
+		}
+	}
+
+	if hintType == constants.HINT_FREEZE && !replay {
+		if err := clockFreeze(tx, gameID, playerID, time.Duration(game.TimeFreezeDuration)*time.Second); err != nil {
+			return 500, nil, fmt.Errorf("couldn't freeze the time")
+		}
+	}
+
 	return 200, &hintContent, nil
 }
@@ -0,0 +1,182 @@
+package functionality
+
+import (
+	"backend/constants"
+	"backend/database"
+	"backend/database/entity"
+	"backend/utils"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrIdentityNoEmail    = errors.New("the provider did not share an email address")
+	ErrIdentityEmailTaken = errors.New("an account with this email already exists: log in with your password and link the provider from your profile")
+	ErrIdentityTaken      = errors.New("this account is already linked to another player")
+)
+
+// ExternalIdentity is what an OpenID Connect provider tells about the player that logged in
+type ExternalIdentity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	// Candidates for the username of a new player, the first usable one is taken
+	Usernames []string
+}
+
+var notAlphanumeric = regexp.MustCompile(`[^a-zA-Z0-9]`)
+
+// identityUsername turns the candidates into a valid username that is not taken yet
+func identityUsername(tx *gorm.DB, candidates []string) (string, error) {
+	base := ""
+	for _, candidate := range candidates {
+		base = notAlphanumeric.ReplaceAllString(candidate, "")
+		if len(base) >= 3 {
+			break
+		}
+	}
+	if len(base) < 3 {
+		base = "player"
+	}
+	if len(base) > 24 {
+		base = base[:24]
+	}
+
+	username := base
+	for attempt := 0; attempt < 10; attempt++ {
+		var count int64
+		if err := tx.Model(&entity.Player{}).Where("username = ?", username).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return username, nil
+		}
+		username = fmt.Sprintf("%s%d", base, time.Now().UnixNano()%1000000)
+	}
+
+	return "", errors.New("couldn't find a free username")
+}
+
+func identityLink(tx *gorm.DB, playerID string, identity ExternalIdentity) error {
+	return tx.Create(&entity.PlayerIdentity{
+		Model:     utils.Model{ID: uuid.New().String()},
+		PlayerID:  playerID,
+		Provider:  identity.Provider,
+		Subject:   identity.Subject,
+		Email:     identity.Email,
+		CreatedAt: time.Now(),
+	}).Error
+}
+
+func identityFind(tx *gorm.DB, provider string, subject string) (*entity.PlayerIdentity, error) {
+	var identity entity.PlayerIdentity
+	result := tx.Where("provider = ? AND subject = ?", provider, subject).First(&identity)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &identity, result.Error
+}
+
+// PlayerLoginWithIdentity returns the player linked to the identity. The first time, the identity is
+// linked to the player with the same email if both the provider and the player verified it, otherwise
+// a new player is created.
+// The username of an existing player is never changed.
+func PlayerLoginWithIdentity(database *database.FinalTestinationDB, identity ExternalIdentity) (*entity.Player, error) {
+	var player entity.Player
+
+	err := database.Orm.Transaction(func(tx *gorm.DB) error {
+		linked, err := identityFind(tx, identity.Provider, identity.Subject)
+		if err != nil {
+			return err
+		}
+		if linked != nil {
+			return tx.Where("id = ?", linked.PlayerID).First(&player).Error
+		}
+
+		if identity.Email == "" {
+			return ErrIdentityNoEmail
+		}
+
+		result := tx.Where("email = ?", identity.Email).First(&player)
+		switch {
+		case result.Error == nil:
+			// Whoever controls an unverified email could take over the account with the same address, and whoever
+			// registered an account with an email it does not own would keep its password on the linked account
+			if !identity.EmailVerified || !player.EmailVerified {
+				return ErrIdentityEmailTaken
+			}
+			return identityLink(tx, player.ID, identity)
+
+		case errors.Is(result.Error, gorm.ErrRecordNotFound):
+			username, err := identityUsername(tx, identity.Usernames)
+			if err != nil {
+				return err
+			}
+			// The player can only log in through the provider, until they reset their password
+			password := make([]byte, 24)
+			if _, err := rand.Read(password); err != nil {
+				return err
+			}
+			hashedPassword, err := utils.GenerateHash(base64.RawURLEncoding.EncodeToString(password))
+			if err != nil {
+				return err
+			}
+
+			player = entity.Player{
+				Model:         utils.Model{ID: uuid.New().String()},
+				Username:      username,
+				Password:      hashedPassword,
+				Email:         identity.Email,
+				IconID:        "1",
+				Secure:        false,
+				SameSite:      "None",
+				Role:          constants.ROLE_PLAYER,
+				EmailVerified: identity.EmailVerified,
+			}
+			if err := tx.Create(&player).Error; err != nil {
+				return err
+			}
+			return identityLink(tx, player.ID, identity)
+
+		default:
+			return result.Error
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &player, nil
+}
+
+// PlayerLinkIdentity links the identity to a player that is already logged in
+func PlayerLinkIdentity(database *database.FinalTestinationDB, playerID string, identity ExternalIdentity) error {
+	return database.Orm.Transaction(func(tx *gorm.DB) error {
+		linked, err := identityFind(tx, identity.Provider, identity.Subject)
+		if err != nil {
+			return err
+		}
+		if linked != nil {
+			if linked.PlayerID != playerID {
+				return ErrIdentityTaken
+			}
+			return nil
+		}
+
+		return identityLink(tx, playerID, identity)
+	})
+}
+
+func PlayerGetIdentities(database *database.FinalTestinationDB, playerID string) ([]entity.PlayerIdentity, error) {
+	identities := []entity.PlayerIdentity{}
+	result := database.Orm.Where("player_id = ?", playerID).Order("created_at").Find(&identities)
+	return identities, result.Error
+}
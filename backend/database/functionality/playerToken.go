@@ -0,0 +1,104 @@
+package functionality
+
+import (
+	"backend/constants"
+	"backend/database"
+	"backend/database/entity"
+	"backend/jwt"
+	"backend/utils"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var ErrTokenInvalid = errors.New("the link is invalid, expired or was already used")
+
+// How long the links sent by email stay valid
+var tokenDurations = map[string]time.Duration{
+	constants.TOKEN_PURPOSE_VERIFY_EMAIL:   48 * time.Hour,
+	constants.TOKEN_PURPOSE_RESET_PASSWORD: time.Hour,
+}
+
+// PlayerTokenCreate issues a signed single-use token for the player.
+// The tokens previously issued for the same purpose are invalidated, so that only the last email works.
+func PlayerTokenCreate(database *database.FinalTestinationDB, playerID string, purpose string) (string, error) {
+	now := time.Now()
+	token := entity.PlayerToken{
+		Model:     utils.Model{ID: uuid.New().String()},
+		PlayerID:  playerID,
+		Purpose:   purpose,
+		CreatedAt: now,
+		ExpiresAt: now.Add(tokenDurations[purpose]),
+	}
+
+	err := database.Orm.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&entity.PlayerToken{}).
+			Where("player_id = ? AND purpose = ? AND used_at IS NULL", playerID, purpose).
+			Update("used_at", now).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(&token).Error
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return jwt.GenerateActionToken(playerID, token.ID, purpose, token.ExpiresAt)
+}
+
+// playerTokenConsume marks the token as used and runs `action` in the same transaction,
+// so that the token stays valid if the action fails
+func playerTokenConsume(database *database.FinalTestinationDB, tokenString string, purpose string, action func(tx *gorm.DB, playerID string) error) error {
+	claims, err := jwt.ParseActionToken(tokenString, purpose)
+	if err != nil {
+		return ErrTokenInvalid
+	}
+
+	return database.Orm.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&entity.PlayerToken{}).
+			Where("id = ? AND player_id = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?",
+				claims.ID, claims.Subject, purpose, time.Now()).
+			Update("used_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrTokenInvalid
+		}
+
+		return action(tx, claims.Subject)
+	})
+}
+
+func PlayerVerifyEmail(database *database.FinalTestinationDB, tokenString string) error {
+	return playerTokenConsume(database, tokenString, constants.TOKEN_PURPOSE_VERIFY_EMAIL, func(tx *gorm.DB, playerID string) error {
+		return tx.Model(&entity.Player{}).
+			Where("id = ?", playerID).
+			Update("email_verified", true).Error
+	})
+}
+
+// PlayerResetPassword sets the new password and revokes every session of the player
+func PlayerResetPassword(database *database.FinalTestinationDB, tokenString string, password string) error {
+	hashedPassword, err := utils.GenerateHash(password)
+	if err != nil {
+		return err
+	}
+
+	return playerTokenConsume(database, tokenString, constants.TOKEN_PURPOSE_RESET_PASSWORD, func(tx *gorm.DB, playerID string) error {
+		err := tx.Model(&entity.Player{}).
+			Where("id = ?", playerID).
+			Update("password", hashedPassword).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&entity.Session{}).
+			Where("player_id = ? AND revoked_at IS NULL", playerID).
+			Update("revoked_at", time.Now()).Error
+	})
+}
@@ -0,0 +1,86 @@
+package functionality
+
+import (
+	"backend/constants"
+	"backend/database/entity"
+	"backend/scoring"
+	"errors"
+	"fmt"
+)
+
+var ErrInvalidPreview = errors.New("invalid scoring preview")
+
+// ScoringPreviewDTO is the outcome of completing a level in a given way
+type ScoringPreviewDTO struct {
+	Score      int     `json:"score"`
+	Multiplier float64 `json:"multiplier"`
+	HintCost   int     `json:"hint_cost"`
+	// What the player earns: the score minus the hints
+	Coins int `json:"coins"`
+}
+
+// GameScoringLevel returns what the scoring policy of the game needs to know about it
+func GameScoringLevel(game *entity.Game) scoring.Level {
+	return scoring.Level{
+		MaxScore:         game.MaxScore,
+		WrongAttemptCost: game.WrongAttemptCost,
+		Timeslots: [4]int64{
+			int64(game.PerfectTimeslot),
+			int64(game.GreatTimeslot),
+			int64(game.MediumTimeslot),
+			int64(game.NotSoGoodTimeslot),
+		},
+	}
+}
+
+// GameScoringPreview computes the score of the game with `policy`, for a player that completed it in `seconds`,
+// before subtracting the time freeze, after `attempts` wrong attempts and buying the `hints`
+func GameScoringPreview(game *entity.Game, policy scoring.Policy, seconds int64, attempts int, hints []string) (*ScoringPreviewDTO, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPreview, err)
+	}
+	if seconds < 0 || attempts < 0 {
+		return nil, fmt.Errorf("%w: the time and the attempts cannot be negative", ErrInvalidPreview)
+	}
+
+	preview := ScoringPreviewDTO{}
+	bought := map[string]bool{}
+	textual := 0
+	for _, hint := range hints {
+		// A block can be filled for every position, the textual hints are revealed in order and the decoys are eliminated a few at a time,
+		// while the time freeze is bought once
+		if bought[hint] && hint == constants.HINT_FREEZE {
+			return nil, fmt.Errorf("%w: hint %q can only be bought once", ErrInvalidPreview, hint)
+		}
+		bought[hint] = true
+
+		switch hint {
+		case constants.HINT_TEXTUAL:
+			if textual >= len(game.TextualHints) {
+				return nil, fmt.Errorf("%w: the level has only %d textual hints", ErrInvalidPreview, len(game.TextualHints))
+			}
+			preview.HintCost += game.TextualHints[textual].Price
+			textual++
+		case constants.HINT_FILL:
+			preview.HintCost += game.HintSolutionPrice
+		case constants.HINT_ELIMINATE:
+			if game.EliminateDecoysCount == 0 {
+				return nil, fmt.Errorf("%w: the level has no decoy blocks to eliminate", ErrInvalidPreview)
+			}
+			preview.HintCost += game.EliminateDecoysPrice
+		case constants.HINT_FREEZE:
+			preview.HintCost += game.TimeFreezePrice
+			// The clock stops while the time is frozen
+			seconds -= int64(game.TimeFreezeDuration)
+			if seconds < 0 {
+				seconds = 0
+			}
+		default:
+			return nil, fmt.Errorf("%w: unknown hint %q", ErrInvalidPreview, hint)
+		}
+	}
+
+	preview.Score, preview.Multiplier = policy.Score(GameScoringLevel(game), seconds, attempts)
+	preview.Coins = preview.Score - preview.HintCost
+	return &preview, nil
+}
@@ -0,0 +1,53 @@
+package functionality
+
+import (
+	"backend/database/entity"
+	"backend/scoring"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGameScoringPreview(t *testing.T) {
+	game := entity.Game{
+		MaxScore:             100,
+		WrongAttemptCost:     5,
+		PerfectTimeslot:      60,
+		GreatTimeslot:        120,
+		MediumTimeslot:       180,
+		NotSoGoodTimeslot:    240,
+		TextualHints:         []entity.TextualHint{{Content: "hint", Price: 10}},
+		HintSolutionPrice:    20,
+		TimeFreezePrice:      30,
+		TimeFreezeDuration:   30,
+		EliminateDecoysCount: 2,
+		EliminateDecoysPrice: 5,
+	}
+
+	preview, err := GameScoringPreview(&game, scoring.Policy{}, 70, 1, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, ScoringPreviewDTO{Score: 75, Multiplier: 0.8, HintCost: 0, Coins: 75}, *preview, "Without hints")
+
+	preview, err = GameScoringPreview(&game, scoring.Policy{}, 70, 1, []string{"freeze", "textual"})
+	assert.NoError(t, err)
+	assert.Equal(t, ScoringPreviewDTO{Score: 95, Multiplier: 1, HintCost: 40, Coins: 55}, *preview, "The time freeze is subtracted from the time")
+
+	preview, err = GameScoringPreview(&game, scoring.Policy{}, 70, 1, []string{"eliminate", "eliminate"})
+	assert.NoError(t, err)
+	assert.Equal(t, 10, preview.HintCost, "The decoys can be eliminated more than once")
+
+	_, err = GameScoringPreview(&game, scoring.Policy{}, 70, 1, []string{"freeze", "freeze"})
+	assert.ErrorIs(t, err, ErrInvalidPreview, "A hint bought twice")
+
+	_, err = GameScoringPreview(&game, scoring.Policy{}, 70, 1, []string{"textual", "textual"})
+	assert.ErrorIs(t, err, ErrInvalidPreview, "More textual hints than the level has")
+
+	_, err = GameScoringPreview(&entity.Game{}, scoring.Policy{}, 70, 1, []string{"eliminate"})
+	assert.ErrorIs(t, err, ErrInvalidPreview, "A level without decoys to eliminate")
+
+	_, err = GameScoringPreview(&game, scoring.Policy{}, 70, 1, []string{"solution"})
+	assert.ErrorIs(t, err, ErrInvalidPreview, "An unknown hint")
+
+	_, err = GameScoringPreview(&game, scoring.Policy{Curve: "fuzzy"}, 70, 1, nil)
+	assert.ErrorIs(t, err, ErrInvalidPreview, "An invalid policy")
+}
@@ -0,0 +1,164 @@
+package functionality
+
+import (
+	"backend/database"
+	"backend/database/entity"
+	"backend/utils"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Sessions last as long as the old login cookie did, rotating the refresh token does not extend them
+const SESSION_DURATION = 31 * 24 * time.Hour
+
+var (
+	ErrSessionInvalid = errors.New("invalid or expired session")
+	// ErrSessionReused means that a refresh token was used twice, so it was probably stolen
+	ErrSessionReused = errors.New("refresh token reused, the session has been revoked")
+)
+
+func newRefreshToken() (token string, hash string, err error) {
+	buffer := make([]byte, 32)
+	if _, err := rand.Read(buffer); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(buffer)
+	return token, hashRefreshToken(token), nil
+}
+
+// Refresh tokens are random, so a plain SHA-256 is enough to avoid storing them in clear
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// SessionCreate opens a new session for the player and returns it together with its refresh token
+func SessionCreate(database *database.FinalTestinationDB, playerID string, userAgent string, ip string) (*entity.Session, string, error) {
+	token, hash, err := newRefreshToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	session := entity.Session{
+		Model:            utils.Model{ID: uuid.New().String()},
+		PlayerID:         playerID,
+		RefreshTokenHash: hash,
+		UserAgent:        userAgent,
+		IP:               ip,
+		CreatedAt:        now,
+		LastUsedAt:       now,
+		ExpiresAt:        now.Add(SESSION_DURATION),
+	}
+
+	if err := database.Orm.Create(&session).Error; err != nil {
+		return nil, "", err
+	}
+
+	return &session, token, nil
+}
+
+// SessionRefresh exchanges a refresh token for a new one. Presenting a token that was already
+// exchanged revokes the whole session, since either the legitimate client or an attacker holds a stale copy.
+func SessionRefresh(database *database.FinalTestinationDB, refreshToken string) (*entity.Session, string, error) {
+	hash := hashRefreshToken(refreshToken)
+	token, newHash, err := newRefreshToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	var session entity.Session
+	err = database.Orm.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("refresh_token_hash = ?", hash).
+			First(&session)
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return ErrSessionInvalid
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if session.RevokedAt != nil || time.Now().After(session.ExpiresAt) {
+			return ErrSessionInvalid
+		}
+
+		previous := session.RefreshTokenHash
+		session.PreviousTokenHash = &previous
+		session.RefreshTokenHash = newHash
+		session.LastUsedAt = time.Now()
+		return tx.Save(&session).Error
+	})
+	if errors.Is(err, ErrSessionInvalid) {
+		// Outside of the transaction, otherwise the revocation would be rolled back
+		reused := database.Orm.Model(&entity.Session{}).
+			Where("previous_token_hash = ? AND revoked_at IS NULL", hash).
+			Update("revoked_at", time.Now())
+		if reused.Error != nil {
+			return nil, "", reused.Error
+		}
+		if reused.RowsAffected > 0 {
+			return nil, "", ErrSessionReused
+		}
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &session, token, nil
+}
+
+// SessionIsActive tells whether the session exists, belongs to the player and has been neither revoked nor expired
+func SessionIsActive(database *database.FinalTestinationDB, playerID string, sessionID string) (bool, error) {
+	var count int64
+	result := database.Orm.Model(&entity.Session{}).
+		Where("id = ? AND player_id = ? AND revoked_at IS NULL AND expires_at > ?", sessionID, playerID, time.Now()).
+		Count(&count)
+
+	return count > 0, result.Error
+}
+
+func SessionGetActive(database *database.FinalTestinationDB, playerID string) ([]entity.Session, error) {
+	sessions := []entity.Session{}
+	result := database.Orm.
+		Where("player_id = ? AND revoked_at IS NULL AND expires_at > ?", playerID, time.Now()).
+		Order("last_used_at DESC").
+		Find(&sessions)
+
+	return sessions, result.Error
+}
+
+// SessionRevoke revokes a single session of the player, returning `gorm.ErrRecordNotFound` if there is no such active session
+func SessionRevoke(database *database.FinalTestinationDB, playerID string, sessionID string) error {
+	result := database.Orm.Model(&entity.Session{}).
+		Where("id = ? AND player_id = ? AND revoked_at IS NULL", sessionID, playerID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func SessionRevokeAll(database *database.FinalTestinationDB, playerID string) error {
+	return database.Orm.Model(&entity.Session{}).
+		Where("player_id = ? AND revoked_at IS NULL", playerID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// SessionRevokeByRefreshToken revokes the session of the refresh token, if there is one
+func SessionRevokeByRefreshToken(database *database.FinalTestinationDB, refreshToken string) error {
+	return database.Orm.Model(&entity.Session{}).
+		Where("refresh_token_hash = ? AND revoked_at IS NULL", hashRefreshToken(refreshToken)).
+		Update("revoked_at", time.Now()).Error
+}
@@ -0,0 +1,48 @@
+package functionality
+
+import (
+	"backend/database"
+	"backend/database/entity"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// standingsFromLedger computes the standings of the players that played at least a level from their coin ledger
+const standingsFromLedger = `INSERT INTO player_standings (player_id, score, reached_at)
+	SELECT players.id, COALESCE(SUM(coin_transactions.amount), 0), COALESCE(MAX(coin_transactions.created_at), 'epoch')
+	FROM players
+	LEFT JOIN coin_transactions ON coin_transactions.player_id = players.id
+	WHERE players.id IN (SELECT player_id FROM player_games)`
+
+// standingsJoin adds a player to the standings when it starts its first level, with the coins it already has
+func standingsJoin(tx *gorm.DB, playerID uuid.UUID) error {
+	return tx.Exec(standingsFromLedger+` AND players.id = ?
+		GROUP BY players.id
+		ON CONFLICT (player_id) DO NOTHING`, playerID).Error
+}
+
+// standingsApply adds a transaction of the ledger to the score of its player. The players that never played
+// a level are not in the standings, and are added with their whole ledger by standingsJoin.
+func standingsApply(tx *gorm.DB, transaction *entity.CoinTransaction) error {
+	return tx.Exec(`UPDATE player_standings SET score = score + ?, reached_at = GREATEST(reached_at, ?)
+		WHERE player_id = ?`, transaction.Amount, transaction.CreatedAt, transaction.PlayerID).Error
+}
+
+// StandingsRebuild recomputes the standings from the coin ledger and returns how many players they hold.
+// The transactions recorded meanwhile wait for the rebuild and are then applied on top of it.
+func StandingsRebuild(database *database.FinalTestinationDB) (int, error) {
+	var players int64
+	err := database.Orm.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("LOCK TABLE player_standings IN EXCLUSIVE MODE").Error; err != nil {
+			return err
+		}
+		if err := tx.Where("1 = 1").Delete(&entity.PlayerStanding{}).Error; err != nil {
+			return err
+		}
+		result := tx.Exec(standingsFromLedger + " GROUP BY players.id")
+		players = result.RowsAffected
+		return result.Error
+	})
+	return int(players), err
+}
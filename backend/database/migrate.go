@@ -0,0 +1,254 @@
+package database
+
+import (
+	"backend/database/migrations"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Arbitrary key of the advisory lock taken while migrating, so that two instances
+// started at the same time do not apply the same migration twice
+const migrationLockKey = 7204981
+
+var migrationFileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+var ErrSchemaBehind = errors.New("the database schema is not up to date")
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+}
+
+type schemaMigration struct {
+	Version   int       `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"not null"`
+	AppliedAt time.Time `gorm:"not null;default:CURRENT_TIMESTAMP"`
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// LoadMigrations reads the embedded migrations, sorted by version.
+// Every version must have both an up and a down file, and versions must have no gaps.
+func LoadMigrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrations.FS, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		match := migrationFileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+
+		version, _ := strconv.Atoi(match[1])
+		content, err := fs.ReadFile(migrations.FS, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two different names: %s and %s", version, migration.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	var result []Migration
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s must have both an up and a down file", migration.Version, migration.Name)
+		}
+		result = append(result, *migration)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Version < result[j].Version })
+
+	for i, migration := range result {
+		if migration.Version != i+1 {
+			return nil, fmt.Errorf("migration %d is missing", i+1)
+		}
+	}
+
+	return result, nil
+}
+
+func (db *FinalTestinationDB) createMigrationsTable() error {
+	return db.Orm.Exec(`CREATE TABLE IF NOT EXISTS "schema_migrations" (
+		"version" bigint PRIMARY KEY,
+		"name" text NOT NULL,
+		"applied_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`).Error
+}
+
+func appliedVersions(tx *gorm.DB) (map[int]schemaMigration, error) {
+	var applied []schemaMigration
+	if err := tx.Order("version").Find(&applied).Error; err != nil {
+		return nil, err
+	}
+
+	versions := map[int]schemaMigration{}
+	for _, migration := range applied {
+		versions[migration.Version] = migration
+	}
+	return versions, nil
+}
+
+// MigrationStatus lists every known migration together with the time it was applied, if it was
+func (db *FinalTestinationDB) MigrationStatus() ([]MigrationStatus, error) {
+	all, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	if err := db.createMigrationsTable(); err != nil {
+		return nil, err
+	}
+
+	applied, err := appliedVersions(db.Orm)
+	if err != nil {
+		return nil, err
+	}
+
+	var status []MigrationStatus
+	for _, migration := range all {
+		s := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if a, ok := applied[migration.Version]; ok {
+			s.AppliedAt = &a.AppliedAt
+		}
+		status = append(status, s)
+	}
+	return status, nil
+}
+
+// MigrateUp applies every pending migration, each one in its own transaction.
+// It returns the migrations that were applied.
+func (db *FinalTestinationDB) MigrateUp() ([]Migration, error) {
+	all, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	if err := db.createMigrationsTable(); err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, migration := range all {
+		applied := false
+		err := db.Orm.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", migrationLockKey).Error; err != nil {
+				return err
+			}
+
+			versions, err := appliedVersions(tx)
+			if err != nil {
+				return err
+			}
+			if _, ok := versions[migration.Version]; ok {
+				return nil
+			}
+
+			if err := tx.Exec(migration.Up).Error; err != nil {
+				return err
+			}
+			applied = true
+			return tx.Create(&schemaMigration{Version: migration.Version, Name: migration.Name}).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+		if applied {
+			done = append(done, migration)
+		}
+	}
+
+	return done, nil
+}
+
+// MigrateDown reverts the last `steps` applied migrations, the most recent first.
+// It returns the migrations that were reverted.
+func (db *FinalTestinationDB) MigrateDown(steps int) ([]Migration, error) {
+	all, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	if err := db.createMigrationsTable(); err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for i := len(all) - 1; i >= 0 && len(done) < steps; i-- {
+		migration := all[i]
+		reverted := false
+		err := db.Orm.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", migrationLockKey).Error; err != nil {
+				return err
+			}
+
+			versions, err := appliedVersions(tx)
+			if err != nil {
+				return err
+			}
+			if _, ok := versions[migration.Version]; !ok {
+				return nil
+			}
+
+			if err := tx.Exec(migration.Down).Error; err != nil {
+				return err
+			}
+			reverted = true
+			return tx.Delete(&schemaMigration{}, "version = ?", migration.Version).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("reverting migration %d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+		if reverted {
+			done = append(done, migration)
+		}
+	}
+
+	return done, nil
+}
+
+// CheckSchemaUpToDate returns `ErrSchemaBehind` if some migration has not been applied yet
+func (db *FinalTestinationDB) CheckSchemaUpToDate() error {
+	status, err := db.MigrationStatus()
+	if err != nil {
+		return err
+	}
+
+	pending := 0
+	for _, s := range status {
+		if s.AppliedAt == nil {
+			pending++
+		}
+	}
+	if pending > 0 {
+		return fmt.Errorf("%w: %d migration(s) pending", ErrSchemaBehind, pending)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := LoadMigrations()
+	assert.NoError(t, err)
+	assert.NotEmpty(t, migrations)
+
+	for i, migration := range migrations {
+		assert.Equalf(t, i+1, migration.Version, "Migration %s has the wrong version", migration.Name)
+		assert.NotEmptyf(t, migration.Up, "Migration %d has no up file", migration.Version)
+		assert.NotEmptyf(t, migration.Down, "Migration %d has no down file", migration.Version)
+	}
+}
+
+func TestMigrationFileName(t *testing.T) {
+	tests := []struct {
+		name  string
+		match bool
+	}{
+		{name: "0001_init.up.sql", match: true},
+		{name: "0012_add_sessions.down.sql", match: true},
+		{name: "0001_init.sql", match: false},
+		{name: "init.up.sql", match: false},
+		{name: "migrations.go", match: false},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.match, migrationFileName.MatchString(test.name), test.name)
+	}
+}
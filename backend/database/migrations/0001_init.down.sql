@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS "icons";
+DROP TABLE IF EXISTS "player_games";
+DROP TABLE IF EXISTS "blocks";
+DROP TABLE IF EXISTS "players";
+DROP TABLE IF EXISTS "games";
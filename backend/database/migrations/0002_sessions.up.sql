@@ -0,0 +1,21 @@
+-- Every login opens a session, identified by the `sid` claim of the access tokens.
+-- Only the hash of the refresh token is stored; the hash of the previous one is kept to detect reuse.
+
+CREATE TABLE "sessions" (
+    "id" varchar(36),
+    "player_id" varchar(36) NOT NULL,
+    "refresh_token_hash" text NOT NULL,
+    "previous_token_hash" text,
+    "user_agent" text NOT NULL DEFAULT '',
+    "ip" text NOT NULL DEFAULT '',
+    "created_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "last_used_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "expires_at" timestamptz NOT NULL,
+    "revoked_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_players_sessions" FOREIGN KEY ("player_id") REFERENCES "players"("id") ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX "idx_sessions_refresh_token_hash" ON "sessions" ("refresh_token_hash");
+CREATE INDEX "idx_sessions_previous_token_hash" ON "sessions" ("previous_token_hash");
+CREATE INDEX "idx_sessions_player_id" ON "sessions" ("player_id");
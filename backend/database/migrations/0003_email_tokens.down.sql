@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS "player_tokens";
+
+ALTER TABLE "players" DROP COLUMN IF EXISTS "email_verified";
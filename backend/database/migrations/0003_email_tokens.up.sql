@@ -0,0 +1,17 @@
+-- Email verification and password reset. The tokens sent by email are signed JWTs,
+-- this table only records them so that each one can be used once.
+
+ALTER TABLE "players" ADD COLUMN "email_verified" boolean NOT NULL DEFAULT false;
+
+CREATE TABLE "player_tokens" (
+    "id" varchar(36),
+    "player_id" varchar(36) NOT NULL,
+    "purpose" text NOT NULL,
+    "created_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "expires_at" timestamptz NOT NULL,
+    "used_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_players_player_tokens" FOREIGN KEY ("player_id") REFERENCES "players"("id") ON DELETE CASCADE
+);
+
+CREATE INDEX "idx_player_tokens_player_id_purpose" ON "player_tokens" ("player_id", "purpose");
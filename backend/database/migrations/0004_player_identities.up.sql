@@ -0,0 +1,15 @@
+-- Accounts of external OpenID Connect providers linked to a player, identified by the `sub` claim
+
+CREATE TABLE "player_identities" (
+    "id" varchar(36),
+    "player_id" varchar(36) NOT NULL,
+    "provider" text NOT NULL,
+    "subject" text NOT NULL,
+    "email" text NOT NULL DEFAULT '',
+    "created_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_players_player_identities" FOREIGN KEY ("player_id") REFERENCES "players"("id") ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX "idx_player_identities_provider_subject" ON "player_identities" ("provider", "subject");
+CREATE INDEX "idx_player_identities_player_id" ON "player_identities" ("player_id");
@@ -0,0 +1,19 @@
+-- Every answer submitted through `check-answer`, with the blocks in the submitted order
+
+CREATE TABLE "attempts" (
+    "id" varchar(36),
+    "player_id" varchar(36) NOT NULL,
+    "game_id" varchar(36) NOT NULL,
+    "blocks" jsonb NOT NULL,
+    "matches" jsonb NOT NULL,
+    "correct" boolean NOT NULL,
+    "submitted_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    -- Seconds since the player started the level, NULL if the level was never started
+    "elapsed_seconds" bigint,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_players_attempts" FOREIGN KEY ("player_id") REFERENCES "players"("id") ON DELETE CASCADE,
+    CONSTRAINT "fk_games_attempts" FOREIGN KEY ("game_id") REFERENCES "games"("id") ON DELETE CASCADE
+);
+
+CREATE INDEX "idx_attempts_player_id_game_id" ON "attempts" ("player_id", "game_id", "submitted_at");
+CREATE INDEX "idx_attempts_game_id" ON "attempts" ("game_id");
@@ -0,0 +1,4 @@
+-- Answers that are accepted besides the one defined by the order of the blocks,
+-- as a list of sequences of block contents
+
+ALTER TABLE "games" ADD COLUMN "alternative_solutions" jsonb NOT NULL DEFAULT '[]';
@@ -0,0 +1,3 @@
+ALTER TABLE "games"
+    DROP COLUMN IF EXISTS "checker",
+    DROP COLUMN IF EXISTS "checker_pattern";
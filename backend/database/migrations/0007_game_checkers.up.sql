@@ -0,0 +1,6 @@
+-- How the answers of a game are checked, see the `checkers` package.
+-- `checker_pattern` is the regular expression used by the `regex` checker.
+
+ALTER TABLE "games"
+    ADD COLUMN "checker" text NOT NULL DEFAULT 'exact',
+    ADD COLUMN "checker_pattern" text NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+ALTER TABLE "player_games" DROP COLUMN IF EXISTS "solve_seconds";
+
+DROP TABLE IF EXISTS "clock_events";
@@ -0,0 +1,19 @@
+-- The clock of a level is measured by replaying its events: it runs from `start` or `resume`
+-- to `pause` or `end`, except during the `freeze` windows that go from `at` to `until`.
+
+CREATE TABLE "clock_events" (
+    "id" varchar(36),
+    "player_id" varchar(36) NOT NULL,
+    "game_id" varchar(36) NOT NULL,
+    "kind" text NOT NULL,
+    "at" timestamptz NOT NULL,
+    "until" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_players_clock_events" FOREIGN KEY ("player_id") REFERENCES "players"("id") ON DELETE CASCADE,
+    CONSTRAINT "fk_games_clock_events" FOREIGN KEY ("game_id") REFERENCES "games"("id") ON DELETE CASCADE
+);
+
+CREATE INDEX "idx_clock_events_player_id_game_id" ON "clock_events" ("player_id", "game_id", "at");
+
+-- Seconds on the clock when the level was completed, NULL for the levels completed before the clock existed
+ALTER TABLE "player_games" ADD COLUMN "solve_seconds" bigint;
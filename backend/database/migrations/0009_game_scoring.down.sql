@@ -0,0 +1 @@
+ALTER TABLE "games" DROP COLUMN IF EXISTS "scoring";
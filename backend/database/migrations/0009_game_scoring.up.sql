@@ -0,0 +1,3 @@
+-- The scoring policy of a game, see the `scoring` package. An empty object is the default policy.
+
+ALTER TABLE "games" ADD COLUMN "scoring" jsonb NOT NULL DEFAULT '{}';
@@ -0,0 +1,39 @@
+-- Every change to the coins of a player. The balance is the sum of the amounts:
+-- `earn`, `grant` and `refund` add coins, `spend` removes them.
+
+CREATE TABLE "coin_transactions" (
+    "id" varchar(36),
+    "player_id" varchar(36) NOT NULL,
+    "kind" text NOT NULL,
+    "amount" bigint NOT NULL,
+    "reason" text NOT NULL,
+    -- The level the transaction is about, if any
+    "game_id" varchar(36),
+    -- Free identifier of what caused the transaction, e.g. the player that granted the coins
+    "reference" text,
+    "created_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "chk_coin_transactions_amount" CHECK (
+        ("kind" IN ('earn', 'grant', 'refund') AND "amount" >= 0) OR ("kind" = 'spend' AND "amount" <= 0)
+    ),
+    CONSTRAINT "fk_players_coin_transactions" FOREIGN KEY ("player_id") REFERENCES "players"("id") ON DELETE CASCADE,
+    CONSTRAINT "fk_games_coin_transactions" FOREIGN KEY ("game_id") REFERENCES "games"("id") ON DELETE SET NULL
+);
+
+CREATE INDEX "idx_coin_transactions_player_id" ON "coin_transactions" ("player_id", "created_at");
+
+-- The balances used to be derived from the scores and the prices of the hints stored in `player_games`
+INSERT INTO "coin_transactions" ("id", "player_id", "kind", "amount", "reason", "game_id", "reference", "created_at")
+SELECT gen_random_uuid()::text, "player_id", 'earn', "score", 'level_completed', "game_id", 'backfill', COALESCE("end_time", "start_time")
+FROM "player_games"
+WHERE "score" IS NOT NULL AND ("end_time" IS NOT NULL OR "score" <> 0);
+
+INSERT INTO "coin_transactions" ("id", "player_id", "kind", "amount", "reason", "game_id", "reference", "created_at")
+SELECT gen_random_uuid()::text, "player_id", 'spend', -"textual_hint_points_used", 'hint_textual', "game_id", 'backfill', "start_time"
+FROM "player_games" WHERE "textual_hint_points_used" > 0
+UNION ALL
+SELECT gen_random_uuid()::text, "player_id", 'spend', -"hint_solution_points_used", 'hint_fill', "game_id", 'backfill', "start_time"
+FROM "player_games" WHERE "hint_solution_points_used" > 0
+UNION ALL
+SELECT gen_random_uuid()::text, "player_id", 'spend', -"time_freeze_points_used", 'hint_freeze', "game_id", 'backfill', "start_time"
+FROM "player_games" WHERE "time_freeze_points_used" > 0;
@@ -0,0 +1,14 @@
+-- Requests that can be retried safely: a request sent again with the same key gets the stored response
+-- instead of being executed twice. `request` identifies the body, so that a key cannot be reused for another request.
+
+CREATE TABLE "idempotency_keys" (
+    "player_id" varchar(36) NOT NULL,
+    "key" text NOT NULL,
+    "scope" text NOT NULL,
+    "request" text NOT NULL,
+    -- NULL until the request completes
+    "response" text,
+    "created_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY ("player_id", "key"),
+    CONSTRAINT "fk_players_idempotency_keys" FOREIGN KEY ("player_id") REFERENCES "players"("id") ON DELETE CASCADE
+);
@@ -0,0 +1,14 @@
+ALTER TABLE "player_games"
+    ADD COLUMN IF NOT EXISTS "textual_hint_points_used" bigint NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS "hint_solution_points_used" bigint NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS "time_freeze_points_used" bigint NOT NULL DEFAULT 0;
+
+UPDATE "player_games" SET
+    "textual_hint_points_used" = COALESCE((SELECT SUM("price") FROM "hint_usages" AS hu
+        WHERE hu."player_id" = "player_games"."player_id" AND hu."game_id" = "player_games"."game_id" AND hu."hint_type" = 'textual' AND NOT hu."replay"), 0),
+    "hint_solution_points_used" = COALESCE((SELECT SUM("price") FROM "hint_usages" AS hu
+        WHERE hu."player_id" = "player_games"."player_id" AND hu."game_id" = "player_games"."game_id" AND hu."hint_type" = 'fill' AND NOT hu."replay"), 0),
+    "time_freeze_points_used" = COALESCE((SELECT SUM("price") FROM "hint_usages" AS hu
+        WHERE hu."player_id" = "player_games"."player_id" AND hu."game_id" = "player_games"."game_id" AND hu."hint_type" = 'freeze' AND NOT hu."replay"), 0);
+
+DROP TABLE IF EXISTS "hint_usages";
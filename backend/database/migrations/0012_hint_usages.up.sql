@@ -0,0 +1,41 @@
+-- Every hint bought by a player, with the price paid. A hint can be free, so the price alone does not tell whether
+-- it was used. `replay` marks the hints used after completing the level, which are always free.
+
+CREATE TABLE "hint_usages" (
+    "id" varchar(36),
+    "player_id" varchar(36) NOT NULL,
+    "game_id" varchar(36) NOT NULL,
+    "hint_type" text NOT NULL,
+    "price" bigint NOT NULL,
+    -- The block revealed by a `fill` hint
+    "block_order" bigint,
+    "replay" boolean NOT NULL DEFAULT false,
+    "used_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_players_hint_usages" FOREIGN KEY ("player_id") REFERENCES "players"("id") ON DELETE CASCADE,
+    CONSTRAINT "fk_games_hint_usages" FOREIGN KEY ("game_id") REFERENCES "games"("id") ON DELETE CASCADE
+);
+
+CREATE INDEX "idx_hint_usages_player_id_game_id" ON "hint_usages" ("player_id", "game_id");
+
+-- While a level is played, the textual hint and the time freeze are bought once, and every block is filled once
+CREATE UNIQUE INDEX "idx_hint_usages_once" ON "hint_usages" ("player_id", "game_id", "hint_type")
+    WHERE NOT "replay" AND "hint_type" <> 'fill';
+CREATE UNIQUE INDEX "idx_hint_usages_fill_once" ON "hint_usages" ("player_id", "game_id", "block_order")
+    WHERE NOT "replay" AND "hint_type" = 'fill';
+
+-- The free hints were not recorded, and neither was the block that was filled
+INSERT INTO "hint_usages" ("id", "player_id", "game_id", "hint_type", "price", "used_at")
+SELECT gen_random_uuid()::text, "player_id", "game_id", 'textual', "textual_hint_points_used", "start_time"
+FROM "player_games" WHERE "textual_hint_points_used" > 0
+UNION ALL
+SELECT gen_random_uuid()::text, "player_id", "game_id", 'fill', "hint_solution_points_used", "start_time"
+FROM "player_games" WHERE "hint_solution_points_used" > 0
+UNION ALL
+SELECT gen_random_uuid()::text, "player_id", "game_id", 'freeze', "time_freeze_points_used", "start_time"
+FROM "player_games" WHERE "time_freeze_points_used" > 0;
+
+ALTER TABLE "player_games"
+    DROP COLUMN "textual_hint_points_used",
+    DROP COLUMN "hint_solution_points_used",
+    DROP COLUMN "time_freeze_points_used";
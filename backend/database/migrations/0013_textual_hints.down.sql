@@ -0,0 +1,17 @@
+-- Only the first textual hint of each game is kept
+DROP INDEX IF EXISTS "idx_hint_usages_textual_once";
+DROP INDEX IF EXISTS "idx_hint_usages_freeze_once";
+DELETE FROM "hint_usages" WHERE "hint_type" = 'textual' AND "hint_index" > 0;
+CREATE UNIQUE INDEX "idx_hint_usages_once" ON "hint_usages" ("player_id", "game_id", "hint_type")
+    WHERE NOT "replay" AND "hint_type" <> 'fill';
+ALTER TABLE "hint_usages" DROP COLUMN IF EXISTS "hint_index";
+
+ALTER TABLE "games"
+    ADD COLUMN IF NOT EXISTS "textual_hint" text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS "textual_hint_price" bigint NOT NULL DEFAULT 0;
+
+UPDATE "games" SET
+    "textual_hint" = COALESCE("textual_hints"->0->>'content', ''),
+    "textual_hint_price" = COALESCE(("textual_hints"->0->>'price')::bigint, 0);
+
+ALTER TABLE "games" DROP COLUMN IF EXISTS "textual_hints";
@@ -0,0 +1,20 @@
+-- A game has an ordered list of textual hints, revealed one at a time, each one with its price
+
+ALTER TABLE "games" ADD COLUMN "textual_hints" jsonb NOT NULL DEFAULT '[]';
+
+UPDATE "games" SET "textual_hints" = jsonb_build_array(jsonb_build_object('content', "textual_hint", 'price', "textual_hint_price"))
+WHERE "textual_hint" <> '';
+
+ALTER TABLE "games"
+    DROP COLUMN "textual_hint",
+    DROP COLUMN "textual_hint_price";
+
+-- The position of the textual hint revealed, in the list of the game
+ALTER TABLE "hint_usages" ADD COLUMN "hint_index" bigint;
+UPDATE "hint_usages" SET "hint_index" = 0 WHERE "hint_type" = 'textual';
+
+DROP INDEX "idx_hint_usages_once";
+CREATE UNIQUE INDEX "idx_hint_usages_freeze_once" ON "hint_usages" ("player_id", "game_id")
+    WHERE NOT "replay" AND "hint_type" = 'freeze';
+CREATE UNIQUE INDEX "idx_hint_usages_textual_once" ON "hint_usages" ("player_id", "game_id", "hint_index")
+    WHERE NOT "replay" AND "hint_type" = 'textual';
@@ -0,0 +1,7 @@
+DELETE FROM "hint_usages" WHERE "hint_type" = 'eliminate';
+
+ALTER TABLE "player_games" DROP COLUMN IF EXISTS "eliminated_blocks";
+
+ALTER TABLE "games"
+    DROP COLUMN IF EXISTS "eliminate_decoys_count",
+    DROP COLUMN IF EXISTS "eliminate_decoys_price";
@@ -0,0 +1,7 @@
+-- The eliminate hint removes some decoy blocks from the ones shown to the player, priced per game
+ALTER TABLE "games"
+    ADD COLUMN "eliminate_decoys_count" bigint NOT NULL DEFAULT 0,
+    ADD COLUMN "eliminate_decoys_price" bigint NOT NULL DEFAULT 0;
+
+-- The IDs of the decoy blocks removed for the player
+ALTER TABLE "player_games" ADD COLUMN "eliminated_blocks" jsonb NOT NULL DEFAULT '[]';
@@ -0,0 +1 @@
+ALTER TABLE "player_games" DROP COLUMN IF EXISTS "draft";
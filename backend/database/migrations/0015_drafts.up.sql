@@ -0,0 +1,2 @@
+-- The answer a player is composing, saved so that the level can be continued on another device
+ALTER TABLE "player_games" ADD COLUMN "draft" jsonb;
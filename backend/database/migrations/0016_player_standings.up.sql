@@ -0,0 +1,19 @@
+-- The all-time standings of the players that played at least a level, kept up to date with every transaction of the
+-- coin ledger so that the leaderboard does not sum the whole ledger. `reached_at` is the time of the last transaction.
+
+CREATE TABLE "player_standings" (
+    "player_id" varchar(36),
+    "score" bigint NOT NULL DEFAULT 0,
+    "reached_at" timestamptz NOT NULL DEFAULT 'epoch',
+    PRIMARY KEY ("player_id"),
+    CONSTRAINT "fk_players_player_standings" FOREIGN KEY ("player_id") REFERENCES "players"("id") ON DELETE CASCADE
+);
+
+CREATE INDEX "idx_player_standings_rank" ON "player_standings" ("score" DESC, "reached_at", "player_id");
+
+INSERT INTO "player_standings" ("player_id", "score", "reached_at")
+SELECT "players"."id", COALESCE(SUM("coin_transactions"."amount"), 0), COALESCE(MAX("coin_transactions"."created_at"), 'epoch')
+FROM "players"
+LEFT JOIN "coin_transactions" ON "coin_transactions"."player_id" = "players"."id"
+WHERE "players"."id" IN (SELECT "player_id" FROM "player_games")
+GROUP BY "players"."id";
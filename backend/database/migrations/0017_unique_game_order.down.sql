@@ -0,0 +1 @@
+DROP INDEX IF EXISTS "idx_games_game_order";
@@ -0,0 +1,3 @@
+-- Two levels cannot have the same order, otherwise the next level of a level is ambiguous.
+-- The order was only checked before saving a level, which concurrent saves could both pass.
+CREATE UNIQUE INDEX "idx_games_game_order" ON "games" ("game_order");
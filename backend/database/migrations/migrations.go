@@ -0,0 +1,11 @@
+// Package migrations contains the versioned SQL migrations of the database schema.
+//
+// Each migration is made of two files, `<version>_<name>.up.sql` and `<version>_<name>.down.sql`,
+// where the version is a number that increases by one for every new migration.
+// Migrations that were already applied must never be edited: add a new one instead.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
@@ -10,3 +10,21 @@ var DB_USERNAME = utils.GetEnv("DB_USERNAME")
 var DB_PASSWORD = utils.GetEnv("DB_PASSWORD")
 var DB_NAME = utils.GetEnv("DB_NAME")
 var API_PORT = utils.GetEnv("PUBLIC_API_PORT")
+
+// Comma separated list of `kid:algorithm:value`, see `jwt.ParseKeys`
+var JWT_KEYS = utils.GetSecretEnv("JWT_KEYS")
+var JWT_SIGNING_KEY = utils.GetEnv("JWT_SIGNING_KEY")
+
+// Where emails are sent: `smtp`, `file:<directory>` or `memory`, see `mailer.New`
+var MAILER = utils.GetEnvOrDefault("MAILER", "file:mails")
+var MAIL_FROM = utils.GetEnvOrDefault("MAIL_FROM", "The Final Testination <no-reply@testination.com>")
+var SMTP_HOST = utils.GetEnvOrDefault("SMTP_HOST", "localhost")
+var SMTP_PORT = utils.GetEnvOrDefault("SMTP_PORT", "587")
+var SMTP_USERNAME = utils.GetEnvOrDefault("SMTP_USERNAME", "")
+var SMTP_PASSWORD = utils.GetSecretEnvOrDefault("SMTP_PASSWORD", "")
+
+// Base URL of the frontend, used for the links sent by email
+var APP_URL = utils.GetEnvOrDefault("APP_URL", "http://localhost:5173")
+
+// Comma separated names of the OpenID Connect providers, see `oidc.Providers`
+var OIDC_PROVIDERS = utils.GetEnvOrDefault("OIDC_PROVIDERS", "")
@@ -0,0 +1,41 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Event is something that happened in the game, streamed live to the clients.
+// `Data` must be serializable as JSON, so that the events can be sent between backend instances.
+type Event struct {
+	Type string    `json:"type"`
+	At   time.Time `json:"at"`
+	Data any       `json:"data"`
+}
+
+// Hub delivers the published events to every subscriber. The in-process `MemoryHub` only reaches the subscribers
+// of the same backend instance: a hub backed by Postgres LISTEN/NOTIFY would publish with NOTIFY and forward what
+// it LISTENs to its own subscribers.
+type Hub interface {
+	Publish(event Event) error
+	// Subscribe returns the channel of the events published from now on and the function that closes it
+	Subscribe() (<-chan Event, func())
+}
+
+// Current is the hub used by the API
+var Current Hub = NewMemoryHub()
+
+// Publish sends an event of the given type to the subscribers of the current hub
+func Publish(eventType string, data any) error {
+	return Current.Publish(Event{Type: eventType, At: time.Now(), Data: data})
+}
+
+// EncodeSSE formats an event as a Server-Sent Event, named after its type
+func EncodeSSE(event Event) ([]byte, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(fmt.Sprintf("event: %s\ndata: %s\n\n", event.Type, data)), nil
+}
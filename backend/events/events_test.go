@@ -0,0 +1,40 @@
+package events
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryHub(t *testing.T) {
+	hub := NewMemoryHub()
+	first, unsubscribeFirst := hub.Subscribe()
+	second, unsubscribeSecond := hub.Subscribe()
+	defer unsubscribeSecond()
+
+	event := Event{Type: "level_completed", At: time.Now(), Data: map[string]int{"score": 100}}
+	assert.NoError(t, hub.Publish(event))
+	assert.Equal(t, event, <-first, "Every subscriber gets the event")
+	assert.Equal(t, event, <-second, "Every subscriber gets the event")
+
+	unsubscribeFirst()
+	unsubscribeFirst()
+	_, open := <-first
+	assert.False(t, open, "Unsubscribing closes the channel")
+
+	for i := 0; i < subscriberBuffer+10; i++ {
+		assert.NoError(t, hub.Publish(event), "A subscriber that does not read does not block the others")
+	}
+	assert.Len(t, second, subscriberBuffer, "The events that do not fit in the buffer are dropped")
+}
+
+func TestEncodeSSE(t *testing.T) {
+	at := time.Date(2024, 5, 6, 10, 0, 0, 0, time.UTC)
+	encoded, err := EncodeSSE(Event{Type: "first_solve", At: at, Data: map[string]string{"username": "root"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "event: first_solve\ndata: {\"type\":\"first_solve\",\"at\":\"2024-05-06T10:00:00Z\",\"data\":{\"username\":\"root\"}}\n\n", string(encoded))
+
+	_, err = EncodeSSE(Event{Type: "broken", Data: make(chan int)})
+	assert.Error(t, err, "The data must be serializable as JSON")
+}
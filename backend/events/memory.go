@@ -0,0 +1,48 @@
+package events
+
+import "sync"
+
+// How many events a subscriber can fall behind before the new ones are dropped
+const subscriberBuffer = 64
+
+// MemoryHub delivers the events to the subscribers of the same process. Publishing never blocks:
+// a subscriber that does not keep up misses the events that do not fit in its buffer.
+type MemoryHub struct {
+	mutex       sync.Mutex
+	subscribers map[chan Event]struct{}
+}
+
+func NewMemoryHub() *MemoryHub {
+	return &MemoryHub{subscribers: map[chan Event]struct{}{}}
+}
+
+func (h *MemoryHub) Publish(event Event) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for subscriber := range h.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+		}
+	}
+	return nil
+}
+
+func (h *MemoryHub) Subscribe() (<-chan Event, func()) {
+	subscriber := make(chan Event, subscriberBuffer)
+
+	h.mutex.Lock()
+	h.subscribers[subscriber] = struct{}{}
+	h.mutex.Unlock()
+
+	var once sync.Once
+	return subscriber, func() {
+		once.Do(func() {
+			h.mutex.Lock()
+			defer h.mutex.Unlock()
+			delete(h.subscribers, subscriber)
+			close(subscriber)
+		})
+	}
+}
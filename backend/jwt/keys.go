@@ -0,0 +1,206 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Key is a key used to sign and/or verify tokens, identified by the `kid` header of the token
+type Key struct {
+	ID     string
+	Method jwt.SigningMethod
+	// nil for asymmetric keys configured with only the public key, which can only verify tokens
+	signKey   any
+	verifyKey any
+}
+
+// KeySet contains every active verification key and the key used to sign new tokens
+type KeySet struct {
+	keys    map[string]*Key
+	signing *Key
+}
+
+// ParseKeys reads a comma separated list of keys in the form `kid:algorithm:value`.
+// For HS256 the value is the secret itself, while for RS256 and EdDSA it is the path of a PEM file
+// containing either a private key, that can sign and verify, or a public key, that can only verify.
+// `signingKeyID` is the ID of the key used to sign new tokens: to rotate keys, add the new key, make it
+// the signing key and remove the old one once all the tokens signed with it have expired.
+func ParseKeys(config string, signingKeyID string) (*KeySet, error) {
+	set := &KeySet{keys: map[string]*Key{}}
+
+	for _, entry := range strings.Split(config, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+			return nil, fmt.Errorf("invalid key %q, expected kid:algorithm:value", entry)
+		}
+		if _, ok := set.keys[parts[0]]; ok {
+			return nil, fmt.Errorf("key %q is defined more than once", parts[0])
+		}
+
+		key, err := parseKey(parts[0], parts[1], parts[2])
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", parts[0], err)
+		}
+		set.keys[key.ID] = key
+	}
+
+	signing, ok := set.keys[signingKeyID]
+	if !ok {
+		return nil, fmt.Errorf("the signing key %q is not defined", signingKeyID)
+	}
+	if signing.signKey == nil {
+		return nil, fmt.Errorf("the signing key %q has no private key", signingKeyID)
+	}
+	set.signing = signing
+
+	return set, nil
+}
+
+func parseKey(id, algorithm, value string) (*Key, error) {
+	switch algorithm {
+	case "HS256":
+		return &Key{ID: id, Method: jwt.SigningMethodHS256, signKey: []byte(value), verifyKey: []byte(value)}, nil
+	case "RS256", "EdDSA":
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", algorithm)
+	}
+
+	content, err := os.ReadFile(value)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, errors.New("the file does not contain a PEM block")
+	}
+
+	var private crypto.Signer
+	var public crypto.PublicKey
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		signer, ok := parsed.(crypto.Signer)
+		if !ok {
+			return nil, errors.New("unsupported private key")
+		}
+		private, public = signer, signer.Public()
+	case "RSA PRIVATE KEY":
+		parsed, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		private, public = parsed, parsed.Public()
+	case "PUBLIC KEY":
+		public, err = x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+
+	key := &Key{ID: id, verifyKey: public}
+	if private != nil {
+		key.signKey = private
+	}
+
+	switch public.(type) {
+	case *rsa.PublicKey:
+		if algorithm != "RS256" {
+			return nil, errors.New("RSA keys can only be used with RS256")
+		}
+		key.Method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		if algorithm != "EdDSA" {
+			return nil, errors.New("Ed25519 keys can only be used with EdDSA")
+		}
+		key.Method = jwt.SigningMethodEdDSA
+	default:
+		return nil, errors.New("unsupported public key")
+	}
+
+	return key, nil
+}
+
+// keyFunc selects the verification key from the `kid` header of the token, making sure that the token
+// was signed with the algorithm of that key. Tokens without `kid` are verified with the signing key.
+func (set *KeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	key := set.signing
+	if kid, ok := token.Header["kid"]; ok {
+		id, _ := kid.(string)
+		key, ok = set.keys[id]
+		if !ok {
+			return nil, fmt.Errorf("unknown key %v", kid)
+		}
+	}
+
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+
+	return key.verifyKey, nil
+}
+
+func (set *KeySet) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(set.signing.Method, claims)
+	token.Header["kid"] = set.signing.ID
+	return token.SignedString(set.signing.signKey)
+}
+
+// PublicJWKS returns the asymmetric public keys as a JSON Web Key Set, so that other services can
+// verify the tokens without knowing any secret. HS256 keys are never published.
+func (set *KeySet) PublicJWKS() map[string]any {
+	keys := []map[string]string{}
+
+	ids := make([]string, 0, len(set.keys))
+	for id := range set.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		key := set.keys[id]
+		switch public := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			keys = append(keys, map[string]string{
+				"kty": "RSA",
+				"kid": key.ID,
+				"alg": key.Method.Alg(),
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			keys = append(keys, map[string]string{
+				"kty": "OKP",
+				"crv": "Ed25519",
+				"kid": key.ID,
+				"alg": key.Method.Alg(),
+				"use": "sig",
+				"x":   base64.RawURLEncoding.EncodeToString(public),
+			})
+		}
+	}
+
+	return map[string]any{"keys": keys}
+}
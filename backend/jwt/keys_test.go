@@ -0,0 +1,170 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func writePEM(t *testing.T, blockType string, der []byte) string {
+	path := filepath.Join(t.TempDir(), "key.pem")
+	err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600)
+	assert.NoError(t, err)
+	return path
+}
+
+func testClaims() FinalTestinationClaims {
+	return FinalTestinationClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+		PlayerID: "a977b9b6-00dd-43de-b9ad-bd1c41ff20be",
+	}
+}
+
+func parseWith(set *KeySet, token string) error {
+	_, err := jwt.ParseWithClaims(token, &FinalTestinationClaims{}, set.keyFunc)
+	return err
+}
+
+func TestParseKeys(t *testing.T) {
+	_, edPrivate, _ := ed25519.GenerateKey(rand.Reader)
+	edPrivateDER, _ := x509.MarshalPKCS8PrivateKey(edPrivate)
+	edPublicDER, _ := x509.MarshalPKIXPublicKey(edPrivate.Public())
+	edPrivatePath := writePEM(t, "PRIVATE KEY", edPrivateDER)
+	edPublicPath := writePEM(t, "PUBLIC KEY", edPublicDER)
+
+	tests := []struct {
+		description string
+		config      string
+		signingKey  string
+		valid       bool
+	}{
+		{
+			description: "A single HS256 key",
+			config:      "dev:HS256:secret",
+			signingKey:  "dev",
+			valid:       true,
+		},
+		{
+			description: "HS256 and EdDSA keys",
+			config:      "old:HS256:secret, new:EdDSA:" + edPrivatePath,
+			signingKey:  "new",
+			valid:       true,
+		},
+		{
+			description: "The signing key is not defined",
+			config:      "dev:HS256:secret",
+			signingKey:  "prod",
+			valid:       false,
+		},
+		{
+			description: "The signing key is only a public key",
+			config:      "dev:EdDSA:" + edPublicPath,
+			signingKey:  "dev",
+			valid:       false,
+		},
+		{
+			description: "An EdDSA key used as RS256",
+			config:      "dev:RS256:" + edPrivatePath,
+			signingKey:  "dev",
+			valid:       false,
+		},
+		{
+			description: "An unsupported algorithm",
+			config:      "dev:none:secret",
+			signingKey:  "dev",
+			valid:       false,
+		},
+		{
+			description: "A key without secret",
+			config:      "dev:HS256:",
+			signingKey:  "dev",
+			valid:       false,
+		},
+		{
+			description: "A key defined twice",
+			config:      "dev:HS256:secret,dev:HS256:other",
+			signingKey:  "dev",
+			valid:       false,
+		},
+	}
+
+	for _, test := range tests {
+		_, err := ParseKeys(test.config, test.signingKey)
+		if test.valid {
+			assert.NoError(t, err, test.description)
+		} else {
+			assert.Error(t, err, test.description)
+		}
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	oldSet, err := ParseKeys("old:HS256:old-secret", "old")
+	assert.NoError(t, err)
+	oldToken, err := oldSet.sign(testClaims())
+	assert.NoError(t, err)
+
+	// The new key signs, the old one is still accepted
+	rotatedSet, err := ParseKeys("old:HS256:old-secret,new:HS256:new-secret", "new")
+	assert.NoError(t, err)
+	newToken, err := rotatedSet.sign(testClaims())
+	assert.NoError(t, err)
+
+	assert.NoError(t, parseWith(rotatedSet, oldToken), "Tokens signed with the old key are still valid")
+	assert.NoError(t, parseWith(rotatedSet, newToken), "Tokens signed with the new key are valid")
+
+	// The old key is removed
+	finalSet, err := ParseKeys("new:HS256:new-secret", "new")
+	assert.NoError(t, err)
+	assert.Error(t, parseWith(finalSet, oldToken), "Tokens signed with a removed key are rejected")
+	assert.NoError(t, parseWith(finalSet, newToken), "Tokens signed with the new key are still valid")
+}
+
+func TestAlgorithmConfusion(t *testing.T) {
+	rsaPrivate, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	rsaPublicDER, _ := x509.MarshalPKIXPublicKey(&rsaPrivate.PublicKey)
+	rsaPublicPath := writePEM(t, "PUBLIC KEY", rsaPublicDER)
+
+	set, err := ParseKeys("hs:HS256:secret,rs:RS256:"+rsaPublicPath, "hs")
+	assert.NoError(t, err)
+
+	// A token signed with HS256 that claims to use the RSA key must be rejected
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims())
+	token.Header["kid"] = "rs"
+	signed, err := token.SignedString([]byte("secret"))
+	assert.NoError(t, err)
+	assert.Error(t, parseWith(set, signed))
+
+	// Only the asymmetric key is published
+	jwks := set.PublicJWKS()["keys"].([]map[string]string)
+	assert.Equal(t, 1, len(jwks))
+	assert.Equal(t, "rs", jwks[0]["kid"])
+}
+
+func TestEdDSATokens(t *testing.T) {
+	_, edPrivate, _ := ed25519.GenerateKey(rand.Reader)
+	edPrivateDER, _ := x509.MarshalPKCS8PrivateKey(edPrivate)
+	edPublicDER, _ := x509.MarshalPKIXPublicKey(edPrivate.Public())
+
+	signer, err := ParseKeys("ed:EdDSA:"+writePEM(t, "PRIVATE KEY", edPrivateDER), "ed")
+	assert.NoError(t, err)
+	token, err := signer.sign(testClaims())
+	assert.NoError(t, err)
+
+	// Another service that only knows the public key can verify the token
+	verifier, err := ParseKeys("ed:EdDSA:"+writePEM(t, "PUBLIC KEY", edPublicDER)+",local:HS256:secret", "local")
+	assert.NoError(t, err)
+	assert.NoError(t, parseWith(verifier, token))
+}
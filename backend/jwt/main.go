@@ -1,62 +1,89 @@
 package jwt
 
 import (
+	"backend/env"
+	"backend/loggers"
 	"errors"
-	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-// FIXME: should be an env variable
-const JWT_SECRET = "secret"
+// Access tokens are short lived, the refresh token of the session is used to get a new one
+const ACCESS_TOKEN_DURATION = 15 * time.Minute
+
+var keys = loadKeys()
+
+func loadKeys() *KeySet {
+	set, err := ParseKeys(env.JWT_KEYS, env.JWT_SIGNING_KEY)
+	if err != nil {
+		loggers.Error.Fatalf("Invalid JWT keys: %s", err)
+	}
+	return set
+}
 
 type FinalTestinationClaims struct {
 	jwt.RegisteredClaims
-	PlayerID string `json:"user_id"`
+	PlayerID  string `json:"user_id"`
+	Role      string `json:"role"`
+	SessionID string `json:"sid"`
 }
 
-func GenerateJWT(playerID string) (string, error) {
+func GenerateJWT(playerID string, role string, sessionID string) (string, error) {
 	claims := FinalTestinationClaims{
 		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    "the-final-testination",
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ACCESS_TOKEN_DURATION)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
-		PlayerID: playerID,
+		PlayerID:  playerID,
+		Role:      role,
+		SessionID: sessionID,
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	// Sign and get the complete encoded token as a string using the current signing key
+	return keys.sign(claims)
+}
 
-	// Sign and get the complete encoded token as a string using the secret
-	tokenString, err := token.SignedString([]byte(JWT_SECRET))
-	return tokenString, err
+// ActionClaims are carried by the single-use tokens sent by email.
+// The subject is the player and the ID is the one of the matching `entity.PlayerToken`.
+type ActionClaims struct {
+	jwt.RegisteredClaims
+	Purpose string `json:"purpose"`
 }
 
-func ParseJWT(tokenString string) (*FinalTestinationClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &FinalTestinationClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
+func GenerateActionToken(playerID string, tokenID string, purpose string, expiresAt time.Time) (string, error) {
+	claims := ActionClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "the-final-testination",
+			Subject:   playerID,
+			ID:        tokenID,
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+		Purpose: purpose,
+	}
 
-		return []byte(JWT_SECRET), nil
-	})
+	return keys.sign(claims)
+}
 
-	if err != nil {
+// ParseActionToken verifies the token and checks that it was issued for `purpose`
+func ParseActionToken(tokenString string, purpose string) (*ActionClaims, error) {
+	claims := &ActionClaims{}
+	if _, err := jwt.ParseWithClaims(tokenString, claims, keys.keyFunc); err != nil {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(*FinalTestinationClaims); ok {
-		return claims, nil
-	} else {
+	if claims.Purpose != purpose || claims.Subject == "" || claims.ID == "" {
 		return nil, errors.New("invalid JWT")
 	}
+
+	return claims, nil
 }
 
-func ParseJWTWithClaims(tokenString string) (*FinalTestinationClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &FinalTestinationClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(JWT_SECRET), nil
-	})
+// ParseJWT verifies the token with the key named by its `kid` header, among the active keys
+func ParseJWT(tokenString string) (*FinalTestinationClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &FinalTestinationClaims{}, keys.keyFunc)
 
 	if err != nil {
 		return nil, err
@@ -65,6 +92,11 @@ func ParseJWTWithClaims(tokenString string) (*FinalTestinationClaims, error) {
 	if claims, ok := token.Claims.(*FinalTestinationClaims); ok {
 		return claims, nil
 	} else {
-		return nil, errors.New("couldn't parse claims")
+		return nil, errors.New("invalid JWT")
 	}
 }
+
+// PublicJWKS returns the public keys that can be used by other services to verify our tokens
+func PublicJWKS() map[string]any {
+	return keys.PublicJWKS()
+}
@@ -0,0 +1,33 @@
+package jwt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestActionTokens(t *testing.T) {
+	playerID := "a977b9b6-00dd-43de-b9ad-bd1c41ff20be"
+
+	token, err := GenerateActionToken(playerID, "token-id", "reset-password", time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+
+	claims, err := ParseActionToken(token, "reset-password")
+	assert.NoError(t, err)
+	assert.Equal(t, playerID, claims.Subject)
+	assert.Equal(t, "token-id", claims.ID)
+
+	_, err = ParseActionToken(token, "verify-email")
+	assert.Error(t, err, "A token cannot be used for another purpose")
+
+	expired, err := GenerateActionToken(playerID, "token-id", "reset-password", time.Now().Add(-time.Minute))
+	assert.NoError(t, err)
+	_, err = ParseActionToken(expired, "reset-password")
+	assert.Error(t, err, "An expired token is rejected")
+
+	access, err := GenerateJWT(playerID, "player", "session-id")
+	assert.NoError(t, err)
+	_, err = ParseActionToken(access, "reset-password")
+	assert.Error(t, err, "An access token is not an action token")
+}
@@ -0,0 +1,293 @@
+package levels
+
+import (
+	"backend/database/entity"
+	"backend/scoring"
+	"backend/utils"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	FORMAT_YAML = "yaml"
+	FORMAT_JSON = "json"
+)
+
+// Package is the declarative representation of a level, meant to be kept under version control.
+// The same document can be written both as YAML and as JSON.
+type Package struct {
+	ID               string    `json:"id" yaml:"id"`
+	Title            string    `json:"title" yaml:"title"`
+	GameOrder        int       `json:"game_order" yaml:"game_order"`
+	Description      string    `json:"description" yaml:"description"`
+	Story            string    `json:"story" yaml:"story"`
+	Cheatsheet       string    `json:"cheatsheet" yaml:"cheatsheet"`
+	Background       string    `json:"background" yaml:"background"`
+	WinningMessage   string    `json:"winning_message" yaml:"winning_message"`
+	MaxScore         int       `json:"max_score" yaml:"max_score"`
+	WrongAttemptCost int       `json:"wrong_attempt_cost" yaml:"wrong_attempt_cost"`
+	Timeslots        Timeslots `json:"timeslots" yaml:"timeslots"`
+	Hints            Hints     `json:"hints" yaml:"hints"`
+	Blocks           Blocks    `json:"blocks" yaml:"blocks"`
+	Checker          Checker   `json:"checker" yaml:"checker"`
+	// Omitted when the level uses the default policy
+	Scoring scoring.Policy `json:"scoring" yaml:"scoring,omitempty"`
+}
+
+// Timeslots are expressed in seconds
+type Timeslots struct {
+	Perfect   int `json:"perfect" yaml:"perfect"`
+	Great     int `json:"great" yaml:"great"`
+	Medium    int `json:"medium" yaml:"medium"`
+	NotSoGood int `json:"not_so_good" yaml:"not_so_good"`
+}
+
+// Checker selects how the answers are checked, `pattern` is only used by the regex checker
+type Checker struct {
+	Type    string `json:"type" yaml:"type"`
+	Pattern string `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+}
+
+type Hints struct {
+	Textual    TextualHints   `json:"textual" yaml:"textual"`
+	Solution   SolutionHint   `json:"solution" yaml:"solution"`
+	TimeFreeze TimeFreezeHint `json:"time_freeze" yaml:"time_freeze"`
+	Eliminate  EliminateHint  `json:"eliminate" yaml:"eliminate"`
+}
+
+type TextualHint struct {
+	Content string `json:"content" yaml:"content"`
+	Price   int    `json:"price" yaml:"price"`
+}
+
+// TextualHints are revealed in order. Packages written before levels had more than
+// one textual hint have a single hint instead of a list, which is still accepted.
+type TextualHints []TextualHint
+
+func (h *TextualHints) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.MappingNode {
+		var hint TextualHint
+		if err := value.Decode(&hint); err != nil {
+			return err
+		}
+		*h = singleTextualHint(hint)
+		return nil
+	}
+
+	var hints []TextualHint
+	if err := value.Decode(&hints); err != nil {
+		return err
+	}
+	*h = hints
+	return nil
+}
+
+func (h *TextualHints) UnmarshalJSON(data []byte) error {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		var hint TextualHint
+		if err := json.Unmarshal(trimmed, &hint); err != nil {
+			return err
+		}
+		*h = singleTextualHint(hint)
+		return nil
+	}
+
+	var hints []TextualHint
+	if err := json.Unmarshal(data, &hints); err != nil {
+		return err
+	}
+	*h = hints
+	return nil
+}
+
+// singleTextualHint converts the hint of an old package, which was empty when the level had no textual hint
+func singleTextualHint(hint TextualHint) TextualHints {
+	if hint.Content == "" {
+		return TextualHints{}
+	}
+	return TextualHints{hint}
+}
+
+type SolutionHint struct {
+	Price int `json:"price" yaml:"price"`
+}
+
+type TimeFreezeHint struct {
+	Price    int `json:"price" yaml:"price"`
+	Duration int `json:"duration" yaml:"duration"` // In seconds
+}
+
+// EliminateHint removes `count` decoys each time it is bought, a count of 0 disables it
+type EliminateHint struct {
+	Count int `json:"count" yaml:"count"`
+	Price int `json:"price" yaml:"price"`
+}
+
+// Blocks lists the solution in order, marking the blocks that are part of the skeleton,
+// followed by the decoys that are shuffled together with the blocks of the solution.
+// Alternatives are other accepted answers, written as the content of the block in each position.
+type Blocks struct {
+	Solution     []SolutionBlock `json:"solution" yaml:"solution"`
+	Decoys       []string        `json:"decoys" yaml:"decoys"`
+	Alternatives [][]string      `json:"alternatives,omitempty" yaml:"alternatives,omitempty"`
+}
+
+type SolutionBlock struct {
+	Content  string `json:"content" yaml:"content"`
+	Skeleton bool   `json:"skeleton,omitempty" yaml:"skeleton,omitempty"`
+}
+
+var ErrInvalidPackage = errors.New("invalid level package")
+
+func FromGame(game *entity.Game) Package {
+	solution := utils.Filter(game.Blocks, func(b entity.Block) bool { return b.Order != nil })
+	sort.Slice(solution, func(i, j int) bool { return *solution[i].Order < *solution[j].Order })
+
+	decoys := utils.Filter(game.Blocks, func(b entity.Block) bool { return b.Order == nil })
+	sort.SliceStable(decoys, func(i, j int) bool { return decoys[i].Content < decoys[j].Content })
+
+	return Package{
+		ID:               game.ID,
+		Title:            game.Title,
+		GameOrder:        game.GameOrder,
+		Description:      game.Description,
+		Story:            game.Story,
+		Cheatsheet:       game.Cheatsheet,
+		Background:       game.Background,
+		WinningMessage:   game.WinningMessage,
+		MaxScore:         game.MaxScore,
+		WrongAttemptCost: game.WrongAttemptCost,
+		Timeslots: Timeslots{
+			Perfect:   game.PerfectTimeslot,
+			Great:     game.GreatTimeslot,
+			Medium:    game.MediumTimeslot,
+			NotSoGood: game.NotSoGoodTimeslot,
+		},
+		Hints: Hints{
+			Textual: utils.Map(game.TextualHints, func(h entity.TextualHint) TextualHint {
+				return TextualHint{Content: h.Content, Price: h.Price}
+			}),
+			Solution: SolutionHint{
+				Price: game.HintSolutionPrice,
+			},
+			TimeFreeze: TimeFreezeHint{
+				Price:    game.TimeFreezePrice,
+				Duration: game.TimeFreezeDuration,
+			},
+			Eliminate: EliminateHint{
+				Count: game.EliminateDecoysCount,
+				Price: game.EliminateDecoysPrice,
+			},
+		},
+		Blocks: Blocks{
+			Solution: utils.Map(solution, func(b entity.Block) SolutionBlock {
+				return SolutionBlock{Content: b.Content, Skeleton: b.Skeleton}
+			}),
+			Decoys:       utils.Map(decoys, func(b entity.Block) string { return b.Content }),
+			Alternatives: game.AlternativeSolutions,
+		},
+		Checker: Checker{
+			Type:    game.Checker,
+			Pattern: game.CheckerPattern,
+		},
+		Scoring: game.Scoring,
+	}
+}
+
+// Game converts the package back to a game. The IDs of the blocks are left empty,
+// since blocks are always recreated when a level is imported.
+func (p *Package) Game() entity.Game {
+	blocks := []entity.Block{}
+	for i, block := range p.Blocks.Solution {
+		order := uint(i)
+		blocks = append(blocks, entity.Block{
+			Content:  block.Content,
+			Order:    &order,
+			Skeleton: block.Skeleton,
+		})
+	}
+	for _, decoy := range p.Blocks.Decoys {
+		blocks = append(blocks, entity.Block{Content: decoy})
+	}
+
+	return entity.Game{
+		Model:             utils.Model{ID: p.ID},
+		Title:             p.Title,
+		GameOrder:         p.GameOrder,
+		Blocks:            blocks,
+		Story:             p.Story,
+		Cheatsheet:        p.Cheatsheet,
+		MaxScore:          p.MaxScore,
+		Description:       p.Description,
+		Background:        p.Background,
+		WinningMessage:    p.WinningMessage,
+		WrongAttemptCost:  p.WrongAttemptCost,
+		PerfectTimeslot:   p.Timeslots.Perfect,
+		GreatTimeslot:     p.Timeslots.Great,
+		MediumTimeslot:    p.Timeslots.Medium,
+		NotSoGoodTimeslot: p.Timeslots.NotSoGood,
+		TextualHints: utils.Map(p.Hints.Textual, func(h TextualHint) entity.TextualHint {
+			return entity.TextualHint{Content: h.Content, Price: h.Price}
+		}),
+		HintSolutionPrice:    p.Hints.Solution.Price,
+		TimeFreezePrice:      p.Hints.TimeFreeze.Price,
+		TimeFreezeDuration:   p.Hints.TimeFreeze.Duration,
+		EliminateDecoysCount: p.Hints.Eliminate.Count,
+		EliminateDecoysPrice: p.Hints.Eliminate.Price,
+		AlternativeSolutions: p.Blocks.Alternatives,
+		Checker:              p.Checker.Type,
+		CheckerPattern:       p.Checker.Pattern,
+		Scoring:              p.Scoring,
+	}
+}
+
+func Encode(p *Package, format string) ([]byte, error) {
+	switch format {
+	case FORMAT_YAML:
+		var buffer bytes.Buffer
+		encoder := yaml.NewEncoder(&buffer)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(p); err != nil {
+			return nil, err
+		}
+		if err := encoder.Close(); err != nil {
+			return nil, err
+		}
+		return buffer.Bytes(), nil
+	case FORMAT_JSON:
+		return json.MarshalIndent(p, "", "  ")
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+}
+
+// Decode parses a package and checks that it is identified by a valid UUID,
+// which is what makes importing the same package twice update the same game.
+func Decode(data []byte, format string) (*Package, error) {
+	var p Package
+	var err error
+
+	switch format {
+	case FORMAT_YAML:
+		err = yaml.Unmarshal(data, &p)
+	case FORMAT_JSON:
+		err = json.Unmarshal(data, &p)
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPackage, err)
+	}
+
+	if _, err := uuid.Parse(p.ID); err != nil {
+		return nil, fmt.Errorf("%w: id must be a valid UUID", ErrInvalidPackage)
+	}
+
+	return &p, nil
+}
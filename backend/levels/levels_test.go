@@ -0,0 +1,166 @@
+package levels
+
+import (
+	"backend/database/entity"
+	"backend/scoring"
+	"backend/utils"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func order(o uint) *uint {
+	return &o
+}
+
+var exampleGame entity.Game = entity.Game{
+	Model:     utils.Model{ID: "af8e4754-1b84-4fec-bec4-154a3f894b8f"},
+	Title:     "XSS Attack",
+	GameOrder: 1,
+	Blocks: []entity.Block{
+		{Content: "goodcompany"},
+		{Content: "evilcompany", Order: order(2)},
+		{Content: "src=\"http://", Order: order(1), Skeleton: true},
+		{Content: "50%"},
+		{Content: "<iframe", Order: order(0)},
+	},
+	Story:             "====EMAIL FROM WORK====\nFrom: [MarkusPumpkin@greatTesters.com]",
+	Cheatsheet:        "1. Introduction\nCross-site scripting",
+	MaxScore:          100,
+	Description:       "A conspiracy",
+	Background:        "<svg></svg>",
+	WinningMessage:    "Great!",
+	WrongAttemptCost:  5,
+	PerfectTimeslot:   60,
+	GreatTimeslot:     120,
+	MediumTimeslot:    180,
+	NotSoGoodTimeslot: 240,
+	TextualHints: []entity.TextualHint{
+		{Content: "Look at the page", Price: 5},
+		{Content: "Use an iframe", Price: 10},
+	},
+	HintSolutionPrice:    20,
+	TimeFreezePrice:      30,
+	TimeFreezeDuration:   60,
+	EliminateDecoysCount: 1,
+	EliminateDecoysPrice: 15,
+	AlternativeSolutions: [][]string{
+		{"<iframe", "src=\"http://", "goodcompany"},
+	},
+	Checker: "exact",
+	Scoring: scoring.Policy{
+		Curve:   scoring.CURVE_BUCKETS,
+		Buckets: []scoring.Bucket{{UpTo: 30, Multiplier: 1}, {UpTo: 90, Multiplier: 0.5}},
+		Penalty: scoring.PENALTY_NONE,
+	},
+}
+
+func TestFromGame(t *testing.T) {
+	pkg := FromGame(&exampleGame)
+
+	assert.Equal(t, []SolutionBlock{
+		{Content: "<iframe"},
+		{Content: "src=\"http://", Skeleton: true},
+		{Content: "evilcompany"},
+	}, pkg.Blocks.Solution, "The solution is sorted by order")
+	assert.Equal(t, []string{"50%", "goodcompany"}, pkg.Blocks.Decoys, "The decoys are sorted alphabetically")
+	assert.Equal(t, exampleGame.AlternativeSolutions, pkg.Blocks.Alternatives)
+	assert.Equal(t, TextualHints{{Content: "Look at the page", Price: 5}, {Content: "Use an iframe", Price: 10}}, pkg.Hints.Textual)
+	assert.Equal(t, EliminateHint{Count: 1, Price: 15}, pkg.Hints.Eliminate)
+	assert.Equal(t, exampleGame.NotSoGoodTimeslot, pkg.Timeslots.NotSoGood)
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, format := range []string{FORMAT_YAML, FORMAT_JSON} {
+		pkg := FromGame(&exampleGame)
+
+		data, err := Encode(&pkg, format)
+		assert.NoError(t, err, format)
+
+		decoded, err := Decode(data, format)
+		assert.NoError(t, err, format)
+		assert.Equal(t, pkg, *decoded, format)
+
+		// Exporting the imported game again must produce the same document
+		game := decoded.Game()
+		assert.Equal(t, len(exampleGame.Blocks), len(game.Blocks), format)
+
+		exported := FromGame(&game)
+		again, err := Encode(&exported, format)
+		assert.NoError(t, err, format)
+		assert.Equal(t, string(data), string(again), format)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		description string
+		data        string
+		format      string
+		valid       bool
+	}{
+		{
+			description: "A YAML package with a valid ID",
+			data:        "id: af8e4754-1b84-4fec-bec4-154a3f894b8f\ntitle: XSS Attack\n",
+			format:      FORMAT_YAML,
+			valid:       true,
+		},
+		{
+			description: "A JSON package without ID",
+			data:        `{"title": "XSS Attack"}`,
+			format:      FORMAT_JSON,
+			valid:       false,
+		},
+		{
+			description: "A malformed YAML package",
+			data:        "id: [",
+			format:      FORMAT_YAML,
+			valid:       false,
+		},
+	}
+
+	for _, test := range tests {
+		_, err := Decode([]byte(test.data), test.format)
+		if test.valid {
+			assert.NoError(t, err, test.description)
+		} else {
+			assert.True(t, errors.Is(err, ErrInvalidPackage), test.description)
+		}
+	}
+}
+
+func TestDecodeSingleTextualHint(t *testing.T) {
+	tests := []struct {
+		description string
+		data        string
+		format      string
+		expected    TextualHints
+	}{
+		{
+			description: "A YAML package with a single textual hint",
+			data:        "id: af8e4754-1b84-4fec-bec4-154a3f894b8f\nhints:\n  textual:\n    content: Use an iframe\n    price: 10\n",
+			format:      FORMAT_YAML,
+			expected:    TextualHints{{Content: "Use an iframe", Price: 10}},
+		},
+		{
+			description: "A JSON package with a single textual hint",
+			data:        `{"id": "af8e4754-1b84-4fec-bec4-154a3f894b8f", "hints": {"textual": {"content": "Use an iframe", "price": 10}}}`,
+			format:      FORMAT_JSON,
+			expected:    TextualHints{{Content: "Use an iframe", Price: 10}},
+		},
+		{
+			description: "A package with an empty textual hint has no textual hints",
+			data:        "id: af8e4754-1b84-4fec-bec4-154a3f894b8f\nhints:\n  textual:\n    content: ''\n    price: 0\n",
+			format:      FORMAT_YAML,
+			expected:    TextualHints{},
+		},
+	}
+
+	for _, test := range tests {
+		pkg, err := Decode([]byte(test.data), test.format)
+		if assert.NoError(t, err, test.description) {
+			assert.Equal(t, test.expected, pkg.Hints.Textual, test.description)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package mailer
+
+import (
+	"backend/env"
+	"backend/loggers"
+	"fmt"
+	"strings"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends emails, the implementation is chosen with the `MAILER` environment variable
+type Mailer interface {
+	Send(message Message) error
+}
+
+// Current is the mailer used by the API, tests can replace it with a `MemoryMailer`
+var Current = newFromEnv()
+
+func newFromEnv() Mailer {
+	mailer, err := New(env.MAILER)
+	if err != nil {
+		loggers.Error.Fatalf("Invalid mailer: %s", err)
+	}
+	return mailer
+}
+
+// New creates a mailer from its configuration: `smtp` uses the `SMTP_*` environment variables,
+// `file:<directory>` writes every email to a file in the directory and `memory` keeps them in memory.
+func New(config string) (Mailer, error) {
+	kind, argument, _ := strings.Cut(config, ":")
+
+	switch kind {
+	case "smtp":
+		return &SMTPMailer{
+			Host:     env.SMTP_HOST,
+			Port:     env.SMTP_PORT,
+			Username: env.SMTP_USERNAME,
+			Password: env.SMTP_PASSWORD,
+			From:     env.MAIL_FROM,
+		}, nil
+	case "file":
+		if argument == "" {
+			return nil, fmt.Errorf("the file mailer needs a directory, e.g. file:mails")
+		}
+		return &FileMailer{Dir: argument, From: env.MAIL_FROM}, nil
+	case "memory":
+		return &MemoryMailer{}, nil
+	default:
+		return nil, fmt.Errorf("unknown mailer %q", config)
+	}
+}
+
+// format renders the message as a plain text email
+func format(from string, message Message) []byte {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "From: %s\r\n", from)
+	fmt.Fprintf(&builder, "To: %s\r\n", message.To)
+	fmt.Fprintf(&builder, "Subject: %s\r\n", message.Subject)
+	builder.WriteString("MIME-Version: 1.0\r\n")
+	builder.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n")
+	builder.WriteString("\r\n")
+	builder.WriteString(strings.ReplaceAll(message.Body, "\n", "\r\n"))
+	return []byte(builder.String())
+}
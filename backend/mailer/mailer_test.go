@@ -0,0 +1,67 @@
+package mailer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		description string
+		config      string
+		valid       bool
+	}{
+		{description: "SMTP mailer", config: "smtp", valid: true},
+		{description: "File mailer", config: "file:mails", valid: true},
+		{description: "File mailer without directory", config: "file", valid: false},
+		{description: "Memory mailer", config: "memory", valid: true},
+		{description: "Unknown mailer", config: "pigeon", valid: false},
+	}
+
+	for _, test := range tests {
+		_, err := New(test.config)
+		if test.valid {
+			assert.NoError(t, err, test.description)
+		} else {
+			assert.Error(t, err, test.description)
+		}
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mails")
+	mailer := &FileMailer{Dir: dir, From: "Testination <no-reply@testination.com>"}
+
+	err := mailer.Send(Message{To: "player@testination.com", Subject: "Hello", Body: "first line\nsecond line"})
+	assert.NoError(t, err)
+
+	files, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(files))
+	assert.True(t, strings.HasSuffix(files[0].Name(), "player@testination.com.eml"))
+
+	content, err := os.ReadFile(filepath.Join(dir, files[0].Name()))
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "To: player@testination.com\r\n")
+	assert.Contains(t, string(content), "Subject: Hello\r\n")
+	assert.True(t, strings.HasSuffix(string(content), "\r\n\r\nfirst line\r\nsecond line"))
+}
+
+func TestMemoryMailer(t *testing.T) {
+	mailer := &MemoryMailer{}
+
+	_, ok := mailer.Last("player@testination.com")
+	assert.False(t, ok)
+
+	assert.NoError(t, mailer.Send(Message{To: "player@testination.com", Subject: "first"}))
+	assert.NoError(t, mailer.Send(Message{To: "other@testination.com", Subject: "other"}))
+	assert.NoError(t, mailer.Send(Message{To: "player@testination.com", Subject: "second"}))
+
+	message, ok := mailer.Last("player@testination.com")
+	assert.True(t, ok)
+	assert.Equal(t, "second", message.Subject)
+}
@@ -0,0 +1,30 @@
+package mailer
+
+import "sync"
+
+// MemoryMailer keeps the emails it sends, it is meant for tests
+type MemoryMailer struct {
+	mutex    sync.Mutex
+	messages []Message
+}
+
+func (m *MemoryMailer) Send(message Message) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.messages = append(m.messages, message)
+	return nil
+}
+
+// Last returns the last email sent to `to`, if any
+func (m *MemoryMailer) Last(to string) (Message, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		if m.messages[i].To == to {
+			return m.messages[i], true
+		}
+	}
+	return Message{}, false
+}
@@ -2,10 +2,13 @@ package main
 
 import (
 	"backend/api"
+	"backend/commands"
 	"backend/database"
 	"backend/env"
+	"backend/jwt"
 	"backend/loggers"
 	"fmt"
+	"os"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -16,13 +19,34 @@ func Ping(c *fiber.Ctx) error {
 	return c.SendString("pong")
 }
 
+// JWKS publishes the public keys used to sign the tokens
+func JWKS(c *fiber.Ctx) error {
+	return c.JSON(jwt.PublicJWKS())
+}
+
 func main() {
+	loggers.Info.Println("Creating database connection")
+	db := database.CreateFinalTestinationDB(
+		env.DB_USERNAME, env.DB_PASSWORD,
+		env.DB_HOST, env.DB_PORT, env.DB_NAME)
+
+	if len(os.Args) > 1 {
+		if err := commands.Run(db, os.Args[1:]); err != nil {
+			loggers.Error.Fatal(err)
+		}
+		return
+	}
+
+	if err := db.CheckSchemaUpToDate(); err != nil {
+		loggers.Error.Fatalf("%s, run `migrate up` first", err)
+	}
+
 	app := fiber.New()
 
 	app.Use(cors.New(
 		cors.Config{
 			AllowOrigins:     "http://localhost:5173",
-			AllowHeaders:     "Origin, Content-Type, Accept",
+			AllowHeaders:     "Origin, Content-Type, Accept, Idempotency-Key",
 			AllowCredentials: true,
 		},
 	))
@@ -30,12 +54,7 @@ func main() {
 	app.Use(logger.New())
 
 	app.Get("/ping", Ping)
-
-	loggers.Info.Println("Creating database connection")
-	db := database.CreateFinalTestinationDB(
-		env.DB_USERNAME, env.DB_PASSWORD,
-		env.DB_HOST, env.DB_PORT, env.DB_NAME)
-	db.CreateSchemas()
+	app.Get("/.well-known/jwks.json", JWKS)
 
 	blockRouter := app.Group("/blocks")
 	api.SetUpBlocksRoutes(&blockRouter, db)
@@ -49,6 +68,12 @@ func main() {
 	playerRouter := app.Group("/player")
 	api.SetUpPlayerRoutes(&playerRouter, db)
 
+	adminRouter := app.Group("/admin")
+	api.SetUpAdminRoutes(&adminRouter, db)
+
+	eventRouter := app.Group("/events")
+	api.SetUpEventRoutes(&eventRouter)
+
 	port := fmt.Sprintf(":%s", env.API_PORT)
 	loggers.Error.Fatal(app.Listen(port))
 
@@ -3,10 +3,12 @@ package middlewares
 import (
 	"backend/constants"
 	"backend/database"
+	"backend/database/entity"
 	"backend/database/functionality"
 	"backend/jwt"
 	"backend/loggers"
 	"backend/validators"
+	"slices"
 	"strconv"
 
 	"github.com/gofiber/fiber/v2"
@@ -75,7 +77,10 @@ func ValidateBodyAs[T validators.Valid](c *fiber.Ctx) error {
 	return c.Next()
 }
 
+// ValidateJWT checks the access token and that its session has not been revoked.
+// It must be used after `InjectDB`.
 func ValidateJWT(c *fiber.Ctx) error {
+	db := c.Locals("db").(*database.FinalTestinationDB)
 	cookie := c.Cookies(constants.AUTH_COOKIE_NAME)
 
 	if cookie == "" {
@@ -87,6 +92,14 @@ func ValidateJWT(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token"})
 	}
 
+	active, err := functionality.SessionIsActive(db, testinationClaims.PlayerID, testinationClaims.SessionID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Couldn't check the session"})
+	}
+	if !active {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Session revoked or expired"})
+	}
+
 	c.Locals("claims", testinationClaims)
 	return c.Next()
 }
@@ -104,3 +117,23 @@ func CheckValidPlayer(c *fiber.Ctx) error {
 	c.Locals("player", *player)
 	return c.Next()
 }
+
+// RequireRole only lets through players whose role is at least `role`.
+// Both the role in the token and the one currently stored for the player are checked,
+// so that a demoted player loses their privileges without waiting for the token to expire.
+// It must be used after `CheckValidPlayer`.
+func RequireRole(role string) fiber.Handler {
+	required := slices.Index(constants.ROLES, role)
+
+	return func(c *fiber.Ctx) error {
+		claims := c.Locals("claims").(*jwt.FinalTestinationClaims)
+		player := c.Locals("player").(entity.Player)
+
+		if slices.Index(constants.ROLES, claims.Role) < required ||
+			slices.Index(constants.ROLES, player.Role) < required {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You are not allowed to do this"})
+		}
+
+		return c.Next()
+	}
+}
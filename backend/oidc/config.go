@@ -0,0 +1,27 @@
+package oidc
+
+import (
+	"backend/env"
+	"backend/utils"
+	"strings"
+)
+
+// Providers are the configured OpenID Connect providers, by name
+var Providers = loadProviders(env.OIDC_PROVIDERS)
+
+// loadProviders reads `OIDC_<NAME>_ISSUER` and `OIDC_<NAME>_CLIENT_ID` for every comma separated name
+func loadProviders(names string) map[string]*Provider {
+	providers := map[string]*Provider{}
+
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		providers[name] = NewProvider(name, utils.GetEnv(prefix+"ISSUER"), utils.GetEnv(prefix+"CLIENT_ID"))
+	}
+
+	return providers
+}
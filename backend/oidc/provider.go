@@ -0,0 +1,175 @@
+package oidc
+
+import (
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// The keys are fetched again after this long, to follow the rotations of the provider
+	jwksCacheDuration = time.Hour
+	// A token signed with an unknown key triggers a new fetch, at most this often
+	jwksMinRefreshInterval = time.Minute
+)
+
+// Algorithms accepted for ID tokens, symmetric ones and `none` are never accepted
+var validMethods = []string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+var (
+	ErrInvalidToken = errors.New("invalid ID token")
+	// ErrProviderUnavailable means that the keys of the provider could not be fetched
+	ErrProviderUnavailable = errors.New("the provider is unavailable")
+)
+
+// Provider verifies the ID tokens of an OpenID Connect issuer locally, with its public keys
+type Provider struct {
+	Name     string
+	Issuer   string
+	ClientID string
+	Client   *http.Client
+
+	mutex     sync.Mutex
+	jwksURI   string
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+}
+
+// Claims of an ID token that are used to find or create the player
+type Claims struct {
+	jwt.RegisteredClaims
+	Email             string       `json:"email"`
+	EmailVerified     flexibleBool `json:"email_verified"`
+	Name              string       `json:"name"`
+	PreferredUsername string       `json:"preferred_username"`
+}
+
+// flexibleBool accepts both booleans and strings, since some providers send `"true"`
+type flexibleBool bool
+
+func (b *flexibleBool) UnmarshalJSON(data []byte) error {
+	*b = flexibleBool(strings.Trim(string(data), `"`) == "true")
+	return nil
+}
+
+func NewProvider(name string, issuer string, clientID string) *Provider {
+	return &Provider{
+		Name:     name,
+		Issuer:   strings.TrimSuffix(issuer, "/"),
+		ClientID: clientID,
+		Client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Verify checks the signature, issuer, audience and expiration of the ID token
+func (p *Provider) Verify(idToken string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(idToken, claims, p.keyFunc,
+		jwt.WithValidMethods(validMethods),
+		jwt.WithIssuer(p.Issuer),
+		jwt.WithAudience(p.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if errors.Is(err, ErrProviderUnavailable) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidToken, err)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: missing subject", ErrInvalidToken)
+	}
+
+	return claims, nil
+}
+
+func (p *Provider) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if time.Since(p.fetchedAt) > jwksCacheDuration ||
+		(p.findKey(kid) == nil && time.Since(p.fetchedAt) > jwksMinRefreshInterval) {
+		if err := p.refreshKeys(); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrProviderUnavailable, err)
+		}
+	}
+
+	key := p.findKey(kid)
+	if key == nil {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+	return key, nil
+}
+
+// findKey returns the key with the given ID. Tokens without `kid` can only be verified if there is a single key.
+func (p *Provider) findKey(kid string) crypto.PublicKey {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key
+		}
+	}
+	return p.keys[kid]
+}
+
+func (p *Provider) getJSON(url string, target any) error {
+	resp, err := p.Client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s returned %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(target)
+}
+
+// refreshKeys fetches the JWKS, discovering its URL the first time. It must be called with the mutex held.
+func (p *Provider) refreshKeys() error {
+	if p.jwksURI == "" {
+		var discovery struct {
+			Issuer  string `json:"issuer"`
+			JWKSURI string `json:"jwks_uri"`
+		}
+		if err := p.getJSON(p.Issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+			return fmt.Errorf("discovery of %s failed: %w", p.Name, err)
+		}
+		if strings.TrimSuffix(discovery.Issuer, "/") != p.Issuer {
+			return fmt.Errorf("discovery of %s returned the issuer %q", p.Name, discovery.Issuer)
+		}
+		if discovery.JWKSURI == "" {
+			return fmt.Errorf("discovery of %s returned no jwks_uri", p.Name)
+		}
+		p.jwksURI = discovery.JWKSURI
+	}
+
+	var set jsonWebKeySet
+	if err := p.getJSON(p.jwksURI, &set); err != nil {
+		return fmt.Errorf("fetching the keys of %s failed: %w", p.Name, err)
+	}
+
+	keys := map[string]crypto.PublicKey{}
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		// Keys of unsupported types are skipped, the others can still be used
+		if key, err := jwk.publicKey(); err == nil {
+			keys[jwk.Kid] = key
+		}
+	}
+
+	p.keys = keys
+	p.fetchedAt = time.Now()
+	return nil
+}
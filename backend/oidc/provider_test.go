@@ -0,0 +1,215 @@
+package oidc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubIssuer is a minimal OpenID Connect issuer that serves the discovery document and its keys
+type stubIssuer struct {
+	server      *httptest.Server
+	keys        []jsonWebKey
+	jwksFetches atomic.Int32
+}
+
+func newStubIssuer(t *testing.T) *stubIssuer {
+	stub := &stubIssuer{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":   stub.server.URL,
+			"jwks_uri": stub.server.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		stub.jwksFetches.Add(1)
+		json.NewEncoder(w).Encode(jsonWebKeySet{Keys: stub.keys})
+	})
+	stub.server = httptest.NewServer(mux)
+	t.Cleanup(stub.server.Close)
+	return stub
+}
+
+func (s *stubIssuer) addRSAKey(t *testing.T, kid string) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	s.keys = append(s.keys, jsonWebKey{
+		Kty: "RSA",
+		Kid: kid,
+		Use: "sig",
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	})
+	return key
+}
+
+func (s *stubIssuer) addECKey(t *testing.T, kid string) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	s.keys = append(s.keys, jsonWebKey{
+		Kty: "EC",
+		Kid: kid,
+		Crv: "P-256",
+		X:   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+		Y:   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+	})
+	return key
+}
+
+func (s *stubIssuer) claims(audience string) jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":            s.server.URL,
+		"aud":            audience,
+		"sub":            "stub-subject",
+		"email":          "stub@testination.com",
+		"email_verified": true,
+		"exp":            time.Now().Add(time.Hour).Unix(),
+		"iat":            time.Now().Unix(),
+	}
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key any, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	assert.NoError(t, err)
+	return signed
+}
+
+func TestVerify(t *testing.T) {
+	stub := newStubIssuer(t)
+	rsaKey := stub.addRSAKey(t, "rsa")
+	ecKey := stub.addECKey(t, "ec")
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	provider := NewProvider("stub", stub.server.URL, "client-id")
+
+	expired := stub.claims("client-id")
+	expired["exp"] = time.Now().Add(-time.Hour).Unix()
+	noSubject := stub.claims("client-id")
+	delete(noSubject, "sub")
+	otherIssuer := stub.claims("client-id")
+	otherIssuer["iss"] = "https://evil.example.com"
+
+	tests := []struct {
+		description string
+		token       string
+		valid       bool
+	}{
+		{
+			description: "A token signed with an RSA key",
+			token:       sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, stub.claims("client-id")),
+			valid:       true,
+		},
+		{
+			description: "A token signed with an EC key",
+			token:       sign(t, jwt.SigningMethodES256, "ec", ecKey, stub.claims("client-id")),
+			valid:       true,
+		},
+		{
+			description: "A token for another client",
+			token:       sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, stub.claims("other-client")),
+			valid:       false,
+		},
+		{
+			description: "A token from another issuer",
+			token:       sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, otherIssuer),
+			valid:       false,
+		},
+		{
+			description: "An expired token",
+			token:       sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, expired),
+			valid:       false,
+		},
+		{
+			description: "A token without subject",
+			token:       sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, noSubject),
+			valid:       false,
+		},
+		{
+			description: "A token signed with a key of the issuer that is not published",
+			token:       sign(t, jwt.SigningMethodRS256, "rsa", otherKey, stub.claims("client-id")),
+			valid:       false,
+		},
+		{
+			description: "A token signed with HS256, using the client ID as secret",
+			token:       sign(t, jwt.SigningMethodHS256, "rsa", []byte("client-id"), stub.claims("client-id")),
+			valid:       false,
+		},
+		{
+			description: "A token without kid when the issuer has several keys",
+			token:       sign(t, jwt.SigningMethodRS256, "", rsaKey, stub.claims("client-id")),
+			valid:       false,
+		},
+	}
+
+	for _, test := range tests {
+		claims, err := provider.Verify(test.token)
+		if test.valid {
+			assert.NoError(t, err, test.description)
+			assert.Equal(t, "stub-subject", claims.Subject, test.description)
+			assert.Equal(t, "stub@testination.com", claims.Email, test.description)
+			assert.True(t, bool(claims.EmailVerified), test.description)
+		} else {
+			assert.ErrorIs(t, err, ErrInvalidToken, test.description)
+		}
+	}
+}
+
+func TestKeysAreCached(t *testing.T) {
+	stub := newStubIssuer(t)
+	rsaKey := stub.addRSAKey(t, "first")
+	provider := NewProvider("stub", stub.server.URL, "client-id")
+
+	for i := 0; i < 3; i++ {
+		_, err := provider.Verify(sign(t, jwt.SigningMethodRS256, "first", rsaKey, stub.claims("client-id")))
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int32(1), stub.jwksFetches.Load(), "The keys are fetched once")
+
+	// The issuer rotates its keys: an unknown kid triggers a new fetch, but not more than once a minute
+	rotatedKey := stub.addRSAKey(t, "second")
+	rotated := sign(t, jwt.SigningMethodRS256, "second", rotatedKey, stub.claims("client-id"))
+
+	_, err := provider.Verify(rotated)
+	assert.Error(t, err, "The new key is not fetched right after the previous fetch")
+	assert.Equal(t, int32(1), stub.jwksFetches.Load())
+
+	provider.fetchedAt = time.Now().Add(-2 * jwksMinRefreshInterval)
+	_, err = provider.Verify(rotated)
+	assert.NoError(t, err, "The new key is fetched when it is first seen")
+	assert.Equal(t, int32(2), stub.jwksFetches.Load())
+}
+
+func TestFlexibleBool(t *testing.T) {
+	var claims Claims
+	assert.NoError(t, json.Unmarshal([]byte(`{"email_verified": "true"}`), &claims))
+	assert.True(t, bool(claims.EmailVerified))
+	assert.NoError(t, json.Unmarshal([]byte(`{"email_verified": false}`), &claims))
+	assert.False(t, bool(claims.EmailVerified))
+}
+
+func TestProviderUnavailable(t *testing.T) {
+	stub := newStubIssuer(t)
+	rsaKey := stub.addRSAKey(t, "rsa")
+	token := sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, stub.claims("client-id"))
+	stub.server.Close()
+
+	_, err := NewProvider("stub", stub.server.URL, "client-id").Verify(token)
+	assert.ErrorIs(t, err, ErrProviderUnavailable)
+}
@@ -0,0 +1,190 @@
+package scoring
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Curves turn the time used to complete a level into a multiplier of its max score
+const (
+	// The multipliers of TIMESLOT_MULTIPLIERS over the four timeslots of the game
+	CURVE_TIMESLOTS = "timeslots"
+	// Arbitrary (threshold, multiplier) buckets
+	CURVE_BUCKETS = "buckets"
+	// The full score until the grace period ends, then halved every half life
+	CURVE_DECAY = "decay"
+)
+
+// Penalties reduce the score for every wrong attempt
+const (
+	// Subtracts the wrong attempt cost of the game for every wrong attempt
+	PENALTY_LINEAR = "linear"
+	// Removes a fraction of the remaining score for every wrong attempt
+	PENALTY_PROPORTIONAL = "proportional"
+	PENALTY_NONE         = "none"
+)
+
+var CURVES = []string{CURVE_TIMESLOTS, CURVE_BUCKETS, CURVE_DECAY}
+var PENALTIES = []string{PENALTY_LINEAR, PENALTY_PROPORTIONAL, PENALTY_NONE}
+
+// Multipliers of the Perfect, Great, Medium and NotSoGood timeslots
+var TIMESLOT_MULTIPLIERS = [4]float64{1, 0.8, 0.6, 0.4}
+var TIMESLOT_NAMES = [4]string{"perfect", "great", "medium", "not_so_good"}
+
+// The name of the end of the grace period of the decay curve
+const THRESHOLD_GRACE = "grace"
+
+// The minimum score, as a fraction of the max score, when the policy does not set one
+const DEFAULT_FLOOR = 0.2
+
+var ErrInvalidPolicy = errors.New("invalid scoring policy")
+
+type Bucket struct {
+	// The multiplier applies to the levels completed in less than `UpTo` seconds
+	UpTo       int64   `json:"up_to" yaml:"up_to"`
+	Multiplier float64 `json:"multiplier" yaml:"multiplier"`
+}
+
+// Policy decides the score of a completed level. The zero value is the policy every game used
+// before policies existed: timeslots, a floor of 20% of the max score and a linear penalty.
+type Policy struct {
+	Curve           string   `json:"curve,omitempty" yaml:"curve,omitempty"`
+	Buckets         []Bucket `json:"buckets,omitempty" yaml:"buckets,omitempty"`
+	GraceSeconds    int64    `json:"grace_seconds,omitempty" yaml:"grace_seconds,omitempty"`
+	HalfLifeSeconds int64    `json:"half_life_seconds,omitempty" yaml:"half_life_seconds,omitempty"`
+	// A pointer, since 0 is a valid floor
+	Floor       *float64 `json:"floor,omitempty" yaml:"floor,omitempty"`
+	Penalty     string   `json:"penalty,omitempty" yaml:"penalty,omitempty"`
+	PenaltyRate float64  `json:"penalty_rate,omitempty" yaml:"penalty_rate,omitempty"`
+}
+
+// Level is what a policy needs to know about the game
+type Level struct {
+	MaxScore         int
+	WrongAttemptCost int
+	// Perfect, Great, Medium and NotSoGood timeslots, in seconds
+	Timeslots [4]int64
+}
+
+// Threshold is the end of a timeslot or of a bucket: the levels completed in less than `UpTo` seconds,
+// and not within an earlier threshold, get `Multiplier`
+type Threshold struct {
+	Name       string  `json:"name"`
+	UpTo       int64   `json:"up_to"`
+	Multiplier float64 `json:"multiplier"`
+}
+
+func (p *Policy) floor() float64 {
+	if p.Floor == nil {
+		return DEFAULT_FLOOR
+	}
+	return *p.Floor
+}
+
+func (p *Policy) Validate() error {
+	floor := p.floor()
+	if floor < 0 || floor > 1 {
+		return fmt.Errorf("%w: the floor must be between 0 and 1", ErrInvalidPolicy)
+	}
+
+	switch p.Curve {
+	case "", CURVE_TIMESLOTS:
+	case CURVE_BUCKETS:
+		if len(p.Buckets) == 0 {
+			return fmt.Errorf("%w: the buckets curve needs at least one bucket", ErrInvalidPolicy)
+		}
+		for i, bucket := range p.Buckets {
+			if bucket.Multiplier < 0 || bucket.Multiplier > 1 {
+				return fmt.Errorf("%w: the multipliers must be between 0 and 1", ErrInvalidPolicy)
+			}
+			if bucket.UpTo <= 0 || (i > 0 && bucket.UpTo <= p.Buckets[i-1].UpTo) {
+				return fmt.Errorf("%w: the thresholds of the buckets must be positive and increasing", ErrInvalidPolicy)
+			}
+		}
+	case CURVE_DECAY:
+		if p.HalfLifeSeconds <= 0 || p.GraceSeconds < 0 {
+			return fmt.Errorf("%w: the decay curve needs a positive half life and a grace period that is not negative", ErrInvalidPolicy)
+		}
+	default:
+		return fmt.Errorf("%w: unknown curve %q", ErrInvalidPolicy, p.Curve)
+	}
+
+	switch p.Penalty {
+	case "", PENALTY_LINEAR, PENALTY_NONE:
+	case PENALTY_PROPORTIONAL:
+		if p.PenaltyRate <= 0 || p.PenaltyRate > 1 {
+			return fmt.Errorf("%w: the penalty rate must be greater than 0 and at most 1", ErrInvalidPolicy)
+		}
+	default:
+		return fmt.Errorf("%w: unknown penalty %q", ErrInvalidPolicy, p.Penalty)
+	}
+
+	return nil
+}
+
+// Thresholds returns the ends of the timeslots or of the buckets of the curve, in increasing order.
+// The decay curve only has the end of its grace period, since the multiplier keeps decreasing after it.
+func (p *Policy) Thresholds(level Level) []Threshold {
+	thresholds := []Threshold{}
+	switch p.Curve {
+	case "", CURVE_TIMESLOTS:
+		for i, timeslot := range level.Timeslots {
+			thresholds = append(thresholds, Threshold{Name: TIMESLOT_NAMES[i], UpTo: timeslot, Multiplier: TIMESLOT_MULTIPLIERS[i]})
+		}
+	case CURVE_BUCKETS:
+		for i, bucket := range p.Buckets {
+			thresholds = append(thresholds, Threshold{Name: fmt.Sprintf("bucket_%d", i+1), UpTo: bucket.UpTo, Multiplier: bucket.Multiplier})
+		}
+	case CURVE_DECAY:
+		// the end of the grace period is included in it
+		thresholds = append(thresholds, Threshold{Name: THRESHOLD_GRACE, UpTo: p.GraceSeconds + 1, Multiplier: 1})
+	}
+	return thresholds
+}
+
+// ThresholdIndex returns the threshold within which a level completed in `seconds` falls, len(thresholds)
+// when it is slower than all of them
+func ThresholdIndex(thresholds []Threshold, seconds int64) int {
+	for i, threshold := range thresholds {
+		if seconds < threshold.UpTo {
+			return i
+		}
+	}
+	return len(thresholds)
+}
+
+// Multiplier returns the multiplier of a level completed in `seconds`, never lower than the floor
+func (p *Policy) Multiplier(level Level, seconds int64) float64 {
+	multiplier := p.floor()
+
+	switch p.Curve {
+	case "", CURVE_TIMESLOTS, CURVE_BUCKETS:
+		thresholds := p.Thresholds(level)
+		if i := ThresholdIndex(thresholds, seconds); i < len(thresholds) {
+			multiplier = thresholds[i].Multiplier
+		}
+	case CURVE_DECAY:
+		multiplier = 1
+		if seconds > p.GraceSeconds {
+			multiplier = math.Pow(0.5, float64(seconds-p.GraceSeconds)/float64(p.HalfLifeSeconds))
+		}
+	}
+
+	return math.Max(multiplier, p.floor())
+}
+
+// Score returns the score of a level completed in `seconds` after `attempts` wrong attempts, with its time multiplier
+func (p *Policy) Score(level Level, seconds int64, attempts int) (int, float64) {
+	multiplier := p.Multiplier(level, seconds)
+	score := float64(level.MaxScore) * multiplier
+
+	switch p.Penalty {
+	case "", PENALTY_LINEAR:
+		score -= float64(attempts) * float64(level.WrongAttemptCost)
+	case PENALTY_PROPORTIONAL:
+		score *= math.Pow(1-p.PenaltyRate, float64(attempts))
+	}
+
+	return int(math.Max(score, float64(level.MaxScore)*p.floor())), multiplier
+}
@@ -0,0 +1,174 @@
+package scoring
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func floor(f float64) *float64 {
+	return &f
+}
+
+var level = Level{
+	MaxScore:         100,
+	WrongAttemptCost: 5,
+	Timeslots:        [4]int64{60, 120, 180, 240},
+}
+
+func TestScore(t *testing.T) {
+	tests := []struct {
+		description        string
+		policy             Policy
+		seconds            int64
+		attempts           int
+		expectedScore      int
+		expectedMultiplier float64
+	}{
+		{
+			description:        "The default policy gives the full score in the Perfect timeslot",
+			seconds:            30,
+			expectedScore:      100,
+			expectedMultiplier: 1,
+		},
+		{
+			description:        "The default policy subtracts the cost of the wrong attempts",
+			seconds:            90,
+			attempts:           2,
+			expectedScore:      70,
+			expectedMultiplier: 0.8,
+		},
+		{
+			description:        "The default policy never goes below 20% of the max score",
+			seconds:            1000,
+			attempts:           10,
+			expectedScore:      20,
+			expectedMultiplier: 0.2,
+		},
+		{
+			description: "Buckets with a harsher curve and no floor",
+			policy: Policy{
+				Curve:   CURVE_BUCKETS,
+				Buckets: []Bucket{{UpTo: 30, Multiplier: 1}, {UpTo: 60, Multiplier: 0.5}},
+				Floor:   floor(0),
+			},
+			seconds:            45,
+			expectedScore:      50,
+			expectedMultiplier: 0.5,
+		},
+		{
+			description: "After the last bucket the multiplier is the floor",
+			policy: Policy{
+				Curve:   CURVE_BUCKETS,
+				Buckets: []Bucket{{UpTo: 30, Multiplier: 1}},
+				Floor:   floor(0),
+			},
+			seconds:            45,
+			expectedScore:      0,
+			expectedMultiplier: 0,
+		},
+		{
+			description:        "The decay curve gives the full score during the grace period",
+			policy:             Policy{Curve: CURVE_DECAY, GraceSeconds: 30, HalfLifeSeconds: 60},
+			seconds:            30,
+			expectedScore:      100,
+			expectedMultiplier: 1,
+		},
+		{
+			description:        "The decay curve halves the score every half life",
+			policy:             Policy{Curve: CURVE_DECAY, GraceSeconds: 30, HalfLifeSeconds: 60},
+			seconds:            150,
+			expectedScore:      25,
+			expectedMultiplier: 0.25,
+		},
+		{
+			description:        "The proportional penalty removes a fraction for every wrong attempt",
+			policy:             Policy{Penalty: PENALTY_PROPORTIONAL, PenaltyRate: 0.5},
+			seconds:            30,
+			attempts:           2,
+			expectedScore:      25,
+			expectedMultiplier: 1,
+		},
+		{
+			description:        "Wrong attempts can be free",
+			policy:             Policy{Penalty: PENALTY_NONE},
+			seconds:            30,
+			attempts:           20,
+			expectedScore:      100,
+			expectedMultiplier: 1,
+		},
+	}
+
+	for _, test := range tests {
+		score, multiplier := test.policy.Score(level, test.seconds, test.attempts)
+		assert.Equal(t, test.expectedScore, score, test.description)
+		assert.InDelta(t, test.expectedMultiplier, multiplier, 1e-9, test.description)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		description string
+		policy      Policy
+		valid       bool
+	}{
+		{description: "The default policy", policy: Policy{}, valid: true},
+		{description: "A floor above 1", policy: Policy{Floor: floor(1.5)}, valid: false},
+		{description: "An unknown curve", policy: Policy{Curve: "linear"}, valid: false},
+		{description: "Buckets without buckets", policy: Policy{Curve: CURVE_BUCKETS}, valid: false},
+		{
+			description: "Buckets with decreasing thresholds",
+			policy:      Policy{Curve: CURVE_BUCKETS, Buckets: []Bucket{{UpTo: 60, Multiplier: 1}, {UpTo: 30, Multiplier: 0.5}}},
+			valid:       false,
+		},
+		{description: "A decay without half life", policy: Policy{Curve: CURVE_DECAY}, valid: false},
+		{description: "A proportional penalty without rate", policy: Policy{Penalty: PENALTY_PROPORTIONAL}, valid: false},
+		{description: "An unknown penalty", policy: Policy{Penalty: "double"}, valid: false},
+	}
+
+	for _, test := range tests {
+		err := test.policy.Validate()
+		if test.valid {
+			assert.NoError(t, err, test.description)
+		} else {
+			assert.ErrorIs(t, err, ErrInvalidPolicy, test.description)
+		}
+	}
+}
+
+func TestThresholds(t *testing.T) {
+	timeslots := (&Policy{}).Thresholds(level)
+	assert.Equal(t, []Threshold{
+		{Name: "perfect", UpTo: 60, Multiplier: 1},
+		{Name: "great", UpTo: 120, Multiplier: 0.8},
+		{Name: "medium", UpTo: 180, Multiplier: 0.6},
+		{Name: "not_so_good", UpTo: 240, Multiplier: 0.4},
+	}, timeslots, "The default policy ends at the timeslots of the level")
+
+	buckets := (&Policy{Curve: CURVE_BUCKETS, Buckets: []Bucket{{UpTo: 30, Multiplier: 1}, {UpTo: 600, Multiplier: 0.5}}}).Thresholds(level)
+	assert.Equal(t, []Threshold{{Name: "bucket_1", UpTo: 30, Multiplier: 1}, {Name: "bucket_2", UpTo: 600, Multiplier: 0.5}}, buckets,
+		"The buckets ignore the timeslots of the level")
+
+	decay := (&Policy{Curve: CURVE_DECAY, GraceSeconds: 90, HalfLifeSeconds: 60}).Thresholds(level)
+	assert.Equal(t, []Threshold{{Name: THRESHOLD_GRACE, UpTo: 91, Multiplier: 1}}, decay, "The grace period includes its last second")
+
+	tests := []struct {
+		description string
+		thresholds  []Threshold
+		seconds     int64
+		expected    int
+	}{
+		{description: "A level solved instantly is Perfect", thresholds: timeslots, seconds: 0, expected: 0},
+		{description: "The end of a timeslot belongs to the next one", thresholds: timeslots, seconds: 60, expected: 1},
+		{description: "A level solved in the Medium timeslot", thresholds: timeslots, seconds: 179, expected: 2},
+		{description: "A level solved in the NotSoGood timeslot", thresholds: timeslots, seconds: 200, expected: 3},
+		{description: "A level solved after every timeslot", thresholds: timeslots, seconds: 1000, expected: 4},
+		{description: "A level solved in the second bucket", thresholds: buckets, seconds: 300, expected: 1},
+		{description: "A level solved at the end of the grace period", thresholds: decay, seconds: 90, expected: 0},
+		{description: "A level solved after the grace period", thresholds: decay, seconds: 91, expected: 1},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, ThresholdIndex(test.thresholds, test.seconds), test.description)
+	}
+}
@@ -13,3 +13,34 @@ func GetEnv(key string) string {
 	loggers.Info.Printf("Environment variable %s set to %s", key, value)
 	return value
 }
+
+// GetEnvOrDefault is like `GetEnv`, but falls back to `fallback` for optional variables
+func GetEnvOrDefault(key string, fallback string) string {
+	value := os.Getenv(key)
+	if value == "" {
+		value = fallback
+	}
+	loggers.Info.Printf("Environment variable %s set to %s", key, value)
+	return value
+}
+
+// GetSecretEnv is like `GetEnv` for the variables that hold secrets, it only logs that they are set
+func GetSecretEnv(key string) string {
+	value := os.Getenv(key)
+	if value == "" {
+		loggers.Error.Fatalf("Environment variable %s not set", key)
+	}
+	loggers.Info.Printf("Environment variable %s set", key)
+	return value
+}
+
+// GetSecretEnvOrDefault is like `GetEnvOrDefault` for the variables that hold secrets, it only logs whether they are set
+func GetSecretEnvOrDefault(key string, fallback string) string {
+	value := os.Getenv(key)
+	if value == "" {
+		loggers.Info.Printf("Environment variable %s not set, using the default", key)
+		return fallback
+	}
+	loggers.Info.Printf("Environment variable %s set", key)
+	return value
+}
@@ -54,6 +54,8 @@ INSERT INTO "players" ("id", "username", "email", "password", "icon_id") VALUES
 
 -- admin : rootroot , test : rootroot , newUser : rootroot, PleaseRunTests : rootroot
 
+UPDATE "players" SET "role" = 'admin' WHERE "username" = 'admin';
+
 
 
 INSERT INTO "player_games" ("game_id", "player_id","score", "start_time") VALUES
@@ -0,0 +1,20 @@
+// Access tokens only last a few minutes: when a request is rejected,
+// the session is refreshed once and the request is sent again.
+export async function authFetch(input: string, init: RequestInit = {}): Promise<Response> {
+	const options: RequestInit = { ...init, credentials: 'include' };
+
+	const res = await fetch(input, options);
+	if (res.status !== 401) {
+		return res;
+	}
+
+	const refresh = await fetch(new URL('/player/refresh', input), {
+		method: 'POST',
+		credentials: 'include'
+	});
+	if (!refresh.ok) {
+		return res;
+	}
+
+	return fetch(input, options);
+}
@@ -5,4 +5,5 @@ set -xe
 echo "Waiting for the database to be ready..."
 sleep 10
 
+/bin/backend migrate up
 /bin/backend
@@ -5,5 +5,6 @@ set -xe
 GIT_ROOT=$(git rev-parse --show-toplevel)
 
 cd $GIT_ROOT/backend
+env $(xargs < $GIT_ROOT/.env) go run main.go migrate up
 env $(xargs < $GIT_ROOT/.env) go run main.go
 